@@ -2,19 +2,53 @@ package main
 
 import (
 	"context"
+	"errors"
+	"flag"
 	"fmt"
+	"io"
+	"net/http"
 	"os"
+	"path/filepath"
+	"strings"
 	"sync"
 	"time"
 
+	"github.com/cloud-scan/cloudscan-runner/internal/blame"
+	"github.com/cloud-scan/cloudscan-runner/internal/changeset"
 	"github.com/cloud-scan/cloudscan-runner/internal/config"
+	"github.com/cloud-scan/cloudscan-runner/internal/doctor"
 	"github.com/cloud-scan/cloudscan-runner/internal/downloader"
+	"github.com/cloud-scan/cloudscan-runner/internal/enrich"
+	"github.com/cloud-scan/cloudscan-runner/internal/findings"
 	"github.com/cloud-scan/cloudscan-runner/internal/orchestrator"
+	"github.com/cloud-scan/cloudscan-runner/internal/overrides"
+	"github.com/cloud-scan/cloudscan-runner/internal/policy"
+	"github.com/cloud-scan/cloudscan-runner/internal/report"
+	"github.com/cloud-scan/cloudscan-runner/internal/sbom"
 	"github.com/cloud-scan/cloudscan-runner/internal/scanners"
+	"github.com/cloud-scan/cloudscan-runner/internal/snippet"
+	"github.com/cloud-scan/cloudscan-runner/internal/suppress"
 	pb "github.com/cloud-scan/cloudscan-orchestrator/generated/proto"
+	"github.com/google/uuid"
 	log "github.com/sirupsen/logrus"
 )
 
+// Exit codes
+const (
+	exitFindings        = 1 // Local scan found findings at or above the threshold
+	exitUsage           = 2 // Invalid command or arguments
+	exitPolicyViolation = 3 // The scan ran but failed the policy gate
+	exitRuntimeError    = 4 // A scanner failed or the results could not be written
+	exitDoctorProblems  = 1 // Doctor found failed checks (or warnings with --strict)
+)
+
+// errPolicyViolation marks a scan that failed the policy gate
+var errPolicyViolation = errors.New("policy gate failed")
+
+// maxTargetedFiles caps the files passed to a targeted scanner on the command
+// line; larger change sets are scanned in full and filtered instead
+const maxTargetedFiles = 1000
+
 var (
 	version   = "dev"
 	commit    = "unknown"
@@ -22,6 +56,46 @@ var (
 )
 
 func main() {
+	command, args := "job", os.Args[1:]
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		command, args = args[0], args[1:]
+	}
+
+	switch command {
+	case "job":
+		runJob()
+	case "scan":
+		os.Exit(runLocalScan(args))
+	case "doctor":
+		os.Exit(runDoctor(args))
+	case "version":
+		fmt.Printf("cloudscan-runner %s (commit %s, built %s)\n", version, commit, buildDate)
+	case "help":
+		usage(os.Stdout)
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown command %q\n\n", command)
+		usage(os.Stderr)
+		os.Exit(exitUsage)
+	}
+}
+
+// usage prints the available commands
+func usage(w io.Writer) {
+	fmt.Fprint(w, `Usage: cloudscan-runner <command> [arguments]
+
+Commands:
+  job       Run the scan job configured by environment variables (default)
+  scan      Scan a local directory and print the findings
+  doctor    Check the scanner toolchain and the environment
+  version   Print the version
+  help      Print this help
+
+Run "cloudscan-runner <command> -h" for the options of scan and doctor.
+`)
+}
+
+// runJob runs the env-configured scan job dispatched by the orchestrator
+func runJob() {
 	log.SetFormatter(&log.JSONFormatter{})
 	log.SetLevel(log.InfoLevel)
 
@@ -40,39 +114,368 @@ func main() {
 	// Set log level
 	setLogLevel(cfg.LogLevel)
 
+	// Connect to orchestrator
+	log.Info("Connecting to orchestrator")
+	orchClient, err := orchestrator.NewClient(context.Background(), cfg.OrchestratorEndpoint, orchestratorOptions(cfg))
+	if err != nil {
+		log.WithError(err).Fatal("Failed to connect to orchestrator")
+	}
+
 	// Run scanner job
-	if err := runScan(cfg); err != nil {
+	err = runScan(cfg, orchClient)
+	orchClient.Close()
+	if err != nil {
+		if errors.Is(err, errPolicyViolation) {
+			log.WithError(err).Error("Scan failed the policy gate")
+			os.Exit(exitPolicyViolation)
+		}
 		log.WithError(err).Fatal("Scan failed")
-		os.Exit(1)
 	}
 
 	log.Info("Scan completed successfully")
 }
 
-func runScan(cfg *config.Config) error {
-	ctx, cancel := context.WithTimeout(context.Background(), cfg.ScanTimeout)
+// orchestratorOptions returns the security settings of the orchestrator
+// connection; tenant IDs are only sent when known
+func orchestratorOptions(cfg *config.Config) orchestrator.Options {
+	opts := orchestrator.Options{
+		TLS:        cfg.OrchestratorTLS,
+		CAFile:     cfg.OrchestratorCAFile,
+		ServerName: cfg.OrchestratorServerName,
+		CertFile:   cfg.OrchestratorCertFile,
+		KeyFile:    cfg.OrchestratorKeyFile,
+		TokenFile:  cfg.OrchestratorTokenFile,
+	}
+	if cfg.OrganizationID != uuid.Nil {
+		opts.OrganizationID = cfg.OrganizationID.String()
+	}
+	if cfg.ProjectID != uuid.Nil {
+		opts.ProjectID = cfg.ProjectID.String()
+	}
+	return opts
+}
+
+// scanSink receives the status and findings of a scan: the orchestrator in
+// job mode, a local collector in scan mode
+type scanSink interface {
+	UpdateScanStatus(ctx context.Context, scanID uuid.UUID, status pb.ScanStatus, errorMsg string) error
+	CreateFindings(ctx context.Context, scanID uuid.UUID, findings []*pb.Finding) error
+	GetFindings(ctx context.Context, scanID uuid.UUID) ([]*pb.Finding, error)
+	UpdateFindingsCount(ctx context.Context, scanID uuid.UUID, count int32) error
+}
+
+// localSink collects the findings of a local scan instead of uploading them
+type localSink struct {
+	findings []*pb.Finding
+}
+
+func (l *localSink) UpdateScanStatus(ctx context.Context, scanID uuid.UUID, status pb.ScanStatus, errorMsg string) error {
+	log.WithFields(log.Fields{
+		"status": status,
+		"error":  errorMsg,
+	}).Debug("Scan status")
+	return nil
+}
+
+func (l *localSink) CreateFindings(ctx context.Context, scanID uuid.UUID, findings []*pb.Finding) error {
+	l.findings = append(l.findings, findings...)
+	return nil
+}
+
+func (l *localSink) GetFindings(ctx context.Context, scanID uuid.UUID) ([]*pb.Finding, error) {
+	return nil, errors.New("baseline comparison requires an orchestrator")
+}
+
+func (l *localSink) UpdateFindingsCount(ctx context.Context, scanID uuid.UUID, count int32) error {
+	return nil
+}
+
+// runLocalScan runs the scan pipeline on a local directory without an
+// orchestrator, prints the findings and returns the exit code
+func runLocalScan(args []string) int {
+	flags := flag.NewFlagSet("scan", flag.ContinueOnError)
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: cloudscan-runner scan [options] <directory>")
+		flags.PrintDefaults()
+	}
+	scanTypes := flags.String("types", "sast,sca,secrets,license", "comma-separated scan types")
+	format := flags.String("format", "text", "output format: text, json or sarif")
+	output := flags.String("output", "", "write findings to this file instead of stdout")
+	failOn := flags.String("fail-on", "high", "exit 1 on findings of at least this severity (critical, high, medium, low, info or none)")
+	selection := flags.String("scanner-selection", "best", "best (one scanner per type) or all")
+	policyFile := flags.String("policy", "", "policy gate rules (YAML)")
+	suppressionMode := flags.String("suppression-mode", "drop", "drop or info for findings matched by .cloudscanignore")
+	resultsDir := flags.String("results-dir", "", "keep the SARIF report and other results in this directory")
+	timeout := flags.Duration("timeout", 30*time.Minute, "scan timeout")
+	logLevel := flags.String("log-level", "warn", "debug, info, warn or error")
+
+	// Options may follow the directory, which the flag package stops at
+	var positional []string
+	for {
+		if err := flags.Parse(args); err != nil {
+			if errors.Is(err, flag.ErrHelp) {
+				return 0
+			}
+			return exitUsage
+		}
+		if flags.NArg() == 0 {
+			break
+		}
+		positional = append(positional, flags.Arg(0))
+		args = flags.Args()[1:]
+	}
+	if len(positional) != 1 {
+		flags.Usage()
+		return exitUsage
+	}
+
+	threshold, err := parseThreshold(*failOn)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitUsage
+	}
+	if *format != "text" && *format != "json" && *format != "sarif" {
+		fmt.Fprintf(os.Stderr, "Unknown format %q\n", *format)
+		return exitUsage
+	}
+
+	log.SetFormatter(&log.TextFormatter{})
+	setLogLevel(*logLevel)
+
+	sourceDir, err := filepath.Abs(positional[0])
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitUsage
+	}
+	cfg, err := config.NewLocal(sourceDir)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitUsage
+	}
+	cfg.ScanTypes = strings.Split(*scanTypes, ",")
+	cfg.ScannerSelection = *selection
+	cfg.PolicyFile = *policyFile
+	cfg.SuppressionMode = *suppressionMode
+	cfg.ScanTimeout = *timeout
+	cfg.ResultsDir = *resultsDir
+	if cfg.ResultsDir == "" {
+		// Reports are only needed long enough to print them
+		cfg.ResultsDir, err = os.MkdirTemp("", "cloudscan-results-")
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return exitRuntimeError
+		}
+		defer os.RemoveAll(cfg.ResultsDir)
+	}
+
+	sink := &localSink{}
+	scanErr := runScan(cfg, sink)
+
+	// Findings are printed even when some scanners failed
+	if err := writeLocalFindings(cfg, *format, *output, sink.findings); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitRuntimeError
+	}
+
+	switch {
+	case errors.Is(scanErr, errPolicyViolation):
+		fmt.Fprintln(os.Stderr, scanErr)
+		return exitPolicyViolation
+	case scanErr != nil:
+		fmt.Fprintln(os.Stderr, scanErr)
+		return exitRuntimeError
+	}
+
+	if threshold != pb.Severity_SEVERITY_UNSPECIFIED {
+		for _, f := range sink.findings {
+			if f.Severity != pb.Severity_SEVERITY_UNSPECIFIED && f.Severity <= threshold && !suppress.IsSuppressed(f) {
+				return exitFindings
+			}
+		}
+	}
+	return 0
+}
+
+// runDoctor checks the scanner toolchain and the environment the job runs
+// in, and returns the exit code
+func runDoctor(args []string) int {
+	flags := flag.NewFlagSet("doctor", flag.ContinueOnError)
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: cloudscan-runner doctor [options]")
+		flags.PrintDefaults()
+	}
+	format := flags.String("format", "text", "output format: text or json")
+	strict := flags.Bool("strict", false, "treat warnings as problems")
+	minFreeMB := flags.Uint64("min-free-mb", 1024, "free space required in WORK_DIR, in MiB")
+	timeout := flags.Duration("timeout", 2*time.Minute, "timeout of all checks")
+	logLevel := flags.String("log-level", "warn", "debug, info, warn or error")
+	if err := flags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		return exitUsage
+	}
+	if flags.NArg() > 0 || (*format != "text" && *format != "json") {
+		flags.Usage()
+		return exitUsage
+	}
+
+	log.SetFormatter(&log.TextFormatter{})
+	setLogLevel(*logLevel)
+
+	cfg, err := config.LoadEnvironment()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitUsage
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
 	defer cancel()
 
-	// Connect to orchestrator
-	log.Info("Connecting to orchestrator")
-	orchClient, err := orchestrator.NewClient(cfg.OrchestratorEndpoint)
+	result := doctor.Run(ctx, doctor.Options{
+		ManifestDir:           cfg.ScannerManifestDir,
+		WorkDir:               cfg.WorkDir,
+		ResultsDir:            cfg.ResultsDir,
+		MinFreeBytes:          *minFreeMB << 20,
+		PolicyFile:            cfg.PolicyFile,
+		SeverityOverridesFile: cfg.SeverityOverridesFile,
+		EPSSDataFile:          cfg.EPSSDataFile,
+		KEVDataFile:           cfg.KEVDataFile,
+		Orchestrator:          cfg.OrchestratorEndpoint,
+		Connect: func(ctx context.Context) error {
+			client, err := orchestrator.NewClient(ctx, cfg.OrchestratorEndpoint, orchestratorOptions(cfg))
+			if err != nil {
+				return err
+			}
+			return client.Close()
+		},
+	})
+
+	if *format == "json" {
+		err = result.WriteJSON(os.Stdout)
+	} else {
+		err = result.WriteText(os.Stdout)
+	}
 	if err != nil {
-		return fmt.Errorf("failed to connect to orchestrator: %w", err)
+		fmt.Fprintln(os.Stderr, err)
+		return exitRuntimeError
+	}
+
+	if result.Count(doctor.StatusFail) > 0 || (*strict && result.Count(doctor.StatusWarn) > 0) {
+		return exitDoctorProblems
+	}
+	return 0
+}
+
+// parseThreshold parses a --fail-on severity; "none" disables the threshold
+func parseThreshold(name string) (pb.Severity, error) {
+	if strings.EqualFold(name, "none") {
+		return pb.Severity_SEVERITY_UNSPECIFIED, nil
+	}
+	value, ok := pb.Severity_value[strings.ToUpper(name)]
+	if !ok || value == int32(pb.Severity_SEVERITY_UNSPECIFIED) {
+		return 0, fmt.Errorf("unknown severity %q", name)
+	}
+	return pb.Severity(value), nil
+}
+
+// writeLocalFindings prints the findings of a local scan in the requested
+// format to stdout or the output file
+func writeLocalFindings(cfg *config.Config, format, output string, reported []*pb.Finding) error {
+	w := io.Writer(os.Stdout)
+	if output != "" {
+		file, err := os.Create(output)
+		if err != nil {
+			return fmt.Errorf("failed to create output file: %w", err)
+		}
+		defer file.Close()
+		w = file
+	}
+
+	switch format {
+	case "json":
+		return report.WriteFindingsJSON(w, cfg.WorkDir, reported)
+	case "sarif":
+		data, err := os.ReadFile(filepath.Join(cfg.ResultsDir, report.SARIFFileName))
+		if err != nil {
+			return fmt.Errorf("failed to read SARIF report: %w", err)
+		}
+		_, err = w.Write(data)
+		return err
+	default:
+		return report.WriteFindingsText(w, cfg.WorkDir, reported)
 	}
-	defer orchClient.Close()
+}
+
+func runScan(cfg *config.Config, orchClient scanSink) error {
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.ScanTimeout)
+	defer cancel()
 
 	// Update scan status to RUNNING
 	if err := orchClient.UpdateScanStatus(ctx, cfg.ScanID, pb.ScanStatus_RUNNING, ""); err != nil {
 		log.WithError(err).Warn("Failed to update scan status to RUNNING")
 	}
 
-	// Prepare source code (either download artifact or clone from Git)
+	// Load the policy and severity overrides up front, so a broken file
+	// fails the scan before the scanners run rather than after
+	var pol *policy.Policy
+	if cfg.PolicyFile != "" {
+		var err error
+		pol, err = policy.Load(cfg.PolicyFile)
+		if err != nil {
+			orchClient.UpdateScanStatus(ctx, cfg.ScanID, pb.ScanStatus_FAILED, fmt.Sprintf("Invalid policy file: %v", err))
+			return fmt.Errorf("invalid policy file: %w", err)
+		}
+	}
+	var overrideTable *overrides.Table
+	if cfg.SeverityOverridesFile != "" {
+		var err error
+		overrideTable, err = overrides.Load(cfg.SeverityOverridesFile)
+		if err != nil {
+			orchClient.UpdateScanStatus(ctx, cfg.ScanID, pb.ScanStatus_FAILED, fmt.Sprintf("Invalid severity overrides file: %v", err))
+			return fmt.Errorf("invalid severity overrides file: %w", err)
+		}
+	}
+
+	// Prepare source code (download an SBOM or artifact, or clone from Git)
 	dl := downloader.New(cfg.DownloadTimeout)
+	defer dl.Close()
+	dl.SetRetries(cfg.DownloadRetries)
+	if err := dl.SetExtractLimits(downloader.ExtractLimits{
+		MaxTotalBytes: cfg.ExtractMaxBytes,
+		MaxFiles:      cfg.ExtractMaxFiles,
+		MaxFileBytes:  cfg.ExtractMaxFileBytes,
+		MaxRatio:      cfg.ExtractMaxRatio,
+		Links:         downloader.LinkPolicy(cfg.ExtractLinks),
+	}); err != nil {
+		orchClient.UpdateScanStatus(ctx, cfg.ScanID, pb.ScanStatus_FAILED, fmt.Sprintf("Invalid extraction limits: %v", err))
+		return fmt.Errorf("invalid extraction limits: %w", err)
+	}
+	scanTarget := cfg.WorkDir
+	sourceKind := scanners.SourceCode
+	var changes *changeset.ChangeSet
+
+	if cfg.SBOMDownloadURL != "" {
+		// SBOM flow: Download the document and scan its components
+		log.Info("Downloading SBOM")
+		sbomPath := filepath.Join(cfg.WorkDir, "sbom.json")
+		if err := dl.DownloadFile(ctx, cfg.SBOMDownloadURL, sbomPath); err != nil {
+			orchClient.UpdateScanStatus(ctx, cfg.ScanID, pb.ScanStatus_FAILED, fmt.Sprintf("Failed to download SBOM: %v", err))
+			return fmt.Errorf("failed to download SBOM: %w", err)
+		}
 
-	if cfg.SourceDownloadURL != "" {
+		format, err := sbom.DetectFileFormat(sbomPath)
+		if err != nil {
+			orchClient.UpdateScanStatus(ctx, cfg.ScanID, pb.ScanStatus_FAILED, fmt.Sprintf("Invalid SBOM: %v", err))
+			return fmt.Errorf("invalid SBOM: %w", err)
+		}
+		log.WithField("format", format).Info("SBOM downloaded")
+
+		scanTarget = sbomPath
+		sourceKind = scanners.SourceSBOM
+	} else if cfg.SourceDownloadURL != "" {
 		// Artifact flow: Download from presigned URL
 		log.Info("Downloading source code from artifact")
-		if err := dl.DownloadAndExtract(ctx, cfg.SourceDownloadURL, cfg.WorkDir); err != nil {
+		if err := dl.DownloadAndExtract(ctx, cfg.SourceDownloadURL, cfg.WorkDir, cfg.SourceSHA256); err != nil {
 			orchClient.UpdateScanStatus(ctx, cfg.ScanID, pb.ScanStatus_FAILED, fmt.Sprintf("Failed to download source: %v", err))
 			return fmt.Errorf("failed to download source: %w", err)
 		}
@@ -84,33 +487,148 @@ func runScan(cfg *config.Config) error {
 			"commit":   cfg.GitCommit,
 		}).Info("Cloning source code from Git repository")
 
-		if err := dl.CloneGit(ctx, cfg.GitURL, cfg.GitBranch, cfg.GitCommit, cfg.WorkDir); err != nil {
+		// Blame needs history; otherwise the latest commit is enough
+		cloneOpts := downloader.CloneOptions{
+			Depth:      1,
+			Submodules: cfg.GitSubmodules,
+			LFS:        cfg.GitLFS,
+		}
+		if cfg.BlameEnabled {
+			cloneOpts.Depth = cfg.BlameDepth
+		}
+		if cfg.SecretsHistory {
+			cloneOpts.Depth = 0
+			cloneOpts.ShallowSince = cfg.SecretsHistorySince
+		}
+
+		auth := downloader.GitAuth{
+			Username:       cfg.GitUsername,
+			Password:       cfg.GitPassword,
+			SSHKeyFile:     cfg.GitSSHKeyFile,
+			KnownHostsFile: cfg.GitKnownHostsFile,
+		}
+		if err := dl.SetGitAuth(auth); err != nil {
+			orchClient.UpdateScanStatus(ctx, cfg.ScanID, pb.ScanStatus_FAILED, fmt.Sprintf("Invalid Git credentials: %v", err))
+			return fmt.Errorf("invalid Git credentials: %w", err)
+		}
+
+		head, err := dl.CloneGit(ctx, cfg.GitURL, cfg.GitBranch, cfg.GitCommit, cfg.WorkDir, cloneOpts)
+		if err != nil {
 			orchClient.UpdateScanStatus(ctx, cfg.ScanID, pb.ScanStatus_FAILED, fmt.Sprintf("Failed to clone repository: %v", err))
 			return fmt.Errorf("failed to clone repository: %w", err)
 		}
+
+		// Reports and SBOMs name the commit actually scanned
+		log.WithFields(log.Fields{
+			"requested_commit": cfg.GitCommit,
+			"head":             head,
+		}).Info("Scanning resolved commit")
+		cfg.GitCommit = head
+
+		// Pull request flow: limit scanning to the changes since the merge base
+		if cfg.BaseCommit != "" || cfg.BaseBranch != "" {
+			changes, err = changeset.Compute(ctx, cfg.WorkDir, changeset.Base{
+				Commit: cfg.BaseCommit,
+				Branch: cfg.BaseBranch,
+			})
+			if err != nil {
+				log.WithError(err).Warn("Failed to compute change set, scanning the full tree")
+				changes = nil
+			}
+		}
+	} else if cfg.SourcePath != "" && cfg.SourceCopy {
+		// Local flow: Copy pre-mounted source code into the workspace
+		log.WithField("source_path", cfg.SourcePath).Info("Copying source code from local directory")
+		if err := dl.CopyDir(ctx, cfg.SourcePath, cfg.WorkDir); err != nil {
+			orchClient.UpdateScanStatus(ctx, cfg.ScanID, pb.ScanStatus_FAILED, fmt.Sprintf("Failed to copy source: %v", err))
+			return fmt.Errorf("failed to copy source: %w", err)
+		}
+	} else if cfg.SourcePath != "" {
+		// Local flow: Scan pre-mounted source code in place; every later
+		// stage reads the workspace, which is now the source path
+		log.WithField("source_path", cfg.SourcePath).Info("Scanning source code in place")
+		cfg.WorkDir = cfg.SourcePath
+		scanTarget = cfg.SourcePath
 	} else {
 		// This should never happen due to config validation, but handle it anyway
-		errMsg := "No source specified: neither SOURCE_DOWNLOAD_URL nor REPOSITORY_URL provided"
+		errMsg := "No source specified: none of SBOM_DOWNLOAD_URL, SOURCE_DOWNLOAD_URL, REPOSITORY_URL or SOURCE_PATH provided"
 		orchClient.UpdateScanStatus(ctx, cfg.ScanID, pb.ScanStatus_FAILED, errMsg)
-		return fmt.Errorf(errMsg)
+		return errors.New(errMsg)
+	}
+
+	// Register scanners declared by manifest files
+	loaded, err := scanners.LoadManifests(cfg.ScannerManifestDir)
+	if err != nil {
+		log.WithError(err).Warn("Failed to load some scanner manifests")
+	}
+	if loaded > 0 {
+		log.WithField("manifests", loaded).Info("Loaded scanner manifests")
 	}
 
 	// Initialize scanners based on requested scan types
-	scannerList := initializeScanners(cfg.ScanTypes)
+	var scanErrors []string
+
+	scannerList, err := initializeScanners(cfg.ScanTypes, cfg.ScannerSelection, sourceKind)
+	if err != nil {
+		log.WithError(err).Error("Scanner selection incomplete")
+		scanErrors = append(scanErrors, err.Error())
+	}
 	if len(scannerList) == 0 {
-		orchClient.UpdateScanStatus(ctx, cfg.ScanID, pb.ScanStatus_FAILED, "No scanners available for requested scan types")
-		return fmt.Errorf("no scanners available")
+		if err == nil {
+			err = errors.New("no scan types requested")
+		}
+		orchClient.UpdateScanStatus(ctx, cfg.ScanID, pb.ScanStatus_FAILED, fmt.Sprintf("No scanners available for requested scan types: %v", err))
+		return fmt.Errorf("no scanners available: %w", err)
 	}
 
 	log.WithField("scanner_count", len(scannerList)).Info("Initialized scanners")
 
 	// Run scanners in parallel
 	log.Info("Starting parallel scan execution")
-	results := runScannersParallel(ctx, scannerList, cfg.WorkDir)
+	var history *historyMode
+	if cfg.SecretsHistory && sourceKind == scanners.SourceCode {
+		history = &historyMode{reserve: cfg.ScanTimeout / historyReserveFraction}
+	}
+	results := runScannersParallel(ctx, scannerList, scanTarget, changes, history)
+
+	// Re-map severities according to the override table
+	if overrideTable != nil {
+		applySeverityOverrides(overrideTable, results)
+	}
+
+	// Add CVSS, EPSS and KEV context to findings with a CVE ID
+	if err := enrichFindings(cfg, results); err != nil {
+		log.WithError(err).Error("Failed to load enrichment data")
+		scanErrors = append(scanErrors, fmt.Sprintf("Failed to load enrichment data: %v", err))
+	}
+
+	// Fill code snippets with context from the workspace
+	if sourceKind == scanners.SourceCode {
+		fillSnippets(cfg, results)
+	}
+
+	// Attribute findings to the commits that changed and introduced their lines
+	if cfg.BlameEnabled && cfg.GitURL != "" {
+		attributeFindings(ctx, cfg, results)
+	}
+
+	// Apply the repository's suppression file
+	if sourceKind == scanners.SourceCode {
+		expired, err := applySuppressions(cfg, results)
+		if err != nil {
+			log.WithError(err).Error("Failed to apply suppressions")
+			scanErrors = append(scanErrors, fmt.Sprintf("Failed to apply suppressions: %v", err))
+		}
+		if len(expired) > 0 {
+			results = append(results, &scanners.Result{
+				Findings:    expired,
+				ScannerName: suppress.FileName,
+			})
+		}
+	}
 
 	// Collect all findings
 	var allFindings []*pb.Finding
-	var scanErrors []string
 
 	for _, result := range results {
 		if result.Error != nil {
@@ -127,6 +645,39 @@ func runScan(cfg *config.Config) error {
 		allFindings = append(allFindings, result.Findings...)
 	}
 
+	// Export a SARIF report before uploading so it exists even if the upload fails
+	sarifPath, err := report.WriteSARIF(cfg.ResultsDir, report.Metadata{
+		ScanID:    cfg.ScanID.String(),
+		SourceDir: cfg.WorkDir,
+		GitURL:    cfg.GitURL,
+		GitBranch: cfg.GitBranch,
+		GitCommit: cfg.GitCommit,
+	}, results)
+	if err != nil {
+		log.WithError(err).Error("Failed to write SARIF report")
+	} else {
+		log.WithField("path", sarifPath).Info("SARIF report written")
+	}
+
+	// Produce SBOMs for the workspace (a supplied SBOM is not re-generated)
+	if len(cfg.SBOMFormats) > 0 && sourceKind == scanners.SourceCode {
+		if err := generateSBOMs(ctx, cfg); err != nil {
+			log.WithError(err).Error("SBOM generation failed")
+			scanErrors = append(scanErrors, fmt.Sprintf("SBOM generation failed: %v", err))
+		}
+	}
+
+	// Report only findings that are new relative to the baseline scan
+	if cfg.BaselineScanID != uuid.Nil {
+		newFindings, err := compareBaseline(ctx, cfg, orchClient, results, allFindings, changes)
+		if err != nil {
+			log.WithError(err).Error("Baseline comparison failed, uploading all findings")
+			scanErrors = append(scanErrors, fmt.Sprintf("Baseline comparison failed: %v", err))
+		} else {
+			allFindings = newFindings
+		}
+	}
+
 	// Upload findings to orchestrator
 	if len(allFindings) > 0 {
 		log.WithField("total_findings", len(allFindings)).Info("Uploading findings to orchestrator")
@@ -141,13 +692,32 @@ func runScan(cfg *config.Config) error {
 		}
 	}
 
-	// Update final scan status
+	// Evaluate the policy gate, separately from scanner execution errors
+	var verdict *policy.Verdict
+	if pol != nil {
+		verdict = evaluatePolicy(cfg, pol, allFindings)
+	}
+
+	// Update final scan status; a failed policy gate decides the exit code
+	// even when scanners also failed
 	if len(scanErrors) > 0 {
 		errorMsg := fmt.Sprintf("Scan completed with errors: %v", scanErrors)
+		if verdict != nil && !verdict.Passed {
+			errorMsg += ". " + verdict.Summary()
+			orchClient.UpdateScanStatus(ctx, cfg.ScanID, pb.ScanStatus_FAILED, errorMsg)
+			return fmt.Errorf("%w: %s; scan had errors: %v", errPolicyViolation, verdict.Summary(), scanErrors)
+		}
 		orchClient.UpdateScanStatus(ctx, cfg.ScanID, pb.ScanStatus_FAILED, errorMsg)
 		return fmt.Errorf("scan had errors: %v", scanErrors)
 	}
 
+	if verdict != nil && !verdict.Passed {
+		if err := orchClient.UpdateScanStatus(ctx, cfg.ScanID, pb.ScanStatus_COMPLETED, verdict.Summary()); err != nil {
+			log.WithError(err).Warn("Failed to update scan status to COMPLETED")
+		}
+		return fmt.Errorf("%w: %s", errPolicyViolation, verdict.Summary())
+	}
+
 	if err := orchClient.UpdateScanStatus(ctx, cfg.ScanID, pb.ScanStatus_COMPLETED, ""); err != nil {
 		log.WithError(err).Warn("Failed to update scan status to COMPLETED")
 	}
@@ -155,54 +725,46 @@ func runScan(cfg *config.Config) error {
 	return nil
 }
 
-// initializeScanners creates scanner instances based on requested scan types
-func initializeScanners(scanTypes []string) []scanners.Scanner {
-	var scannerList []scanners.Scanner
-
-	for _, scanType := range scanTypes {
-		switch scanType {
-		case "sast", "SAST":
-			scanner := scanners.NewSemgrepScanner()
-			if scanner.IsAvailable() {
-				scannerList = append(scannerList, scanner)
-			} else {
-				log.Warn("Semgrep scanner not available")
-			}
+// evaluatePolicy checks the reported findings against the policy and
+// writes the verdict to the results directory
+func evaluatePolicy(cfg *config.Config, pol *policy.Policy, reported []*pb.Finding) *policy.Verdict {
+	// Findings kept at INFO by a suppression do not count against the policy
+	var gated []*pb.Finding
+	for _, f := range reported {
+		if !suppress.IsSuppressed(f) {
+			gated = append(gated, f)
+		}
+	}
 
-		case "sca", "SCA":
-			scanner := scanners.NewTrivyScanner()
-			if scanner.IsAvailable() {
-				scannerList = append(scannerList, scanner)
-			} else {
-				log.Warn("Trivy scanner not available")
-			}
+	verdict := pol.Evaluate(gated)
+	log.WithFields(log.Fields{
+		"passed":     verdict.Passed,
+		"rules":      len(pol.Rules),
+		"violations": len(verdict.Violations),
+	}).Info(verdict.Summary())
 
-		case "secrets", "SECRETS":
-			scanner := scanners.NewTruffleHogScanner()
-			if scanner.IsAvailable() {
-				scannerList = append(scannerList, scanner)
-			} else {
-				log.Warn("TruffleHog scanner not available")
-			}
+	path, err := report.WritePolicyVerdict(cfg.ResultsDir, verdict)
+	if err != nil {
+		log.WithError(err).Error("Failed to write policy verdict")
+	} else {
+		log.WithField("path", path).Info("Policy verdict written")
+	}
 
-		case "license", "LICENSE":
-			scanner := scanners.NewScanCodeScanner()
-			if scanner.IsAvailable() {
-				scannerList = append(scannerList, scanner)
-			} else {
-				log.Warn("ScanCode scanner not available")
-			}
+	return verdict
+}
 
-		default:
-			log.WithField("scan_type", scanType).Warn("Unknown scan type")
-		}
+// initializeScanners resolves requested scan types to scanners through the registry
+func initializeScanners(scanTypes []string, selection string, source scanners.SourceKind) ([]scanners.Scanner, error) {
+	mode, err := scanners.ParseSelectionMode(selection)
+	if err != nil {
+		return nil, err
 	}
 
-	return scannerList
+	return scanners.Select(scanTypes, mode, source)
 }
 
 // runScannersParallel executes all scanners in parallel using goroutines
-func runScannersParallel(ctx context.Context, scannerList []scanners.Scanner, sourceDir string) []*scanners.Result {
+func runScannersParallel(ctx context.Context, scannerList []scanners.Scanner, sourceDir string, changes *changeset.ChangeSet, history *historyMode) []*scanners.Result {
 	var wg sync.WaitGroup
 	results := make([]*scanners.Result, len(scannerList))
 
@@ -214,21 +776,46 @@ func runScannersParallel(ctx context.Context, scannerList []scanners.Scanner, so
 			startTime := time.Now()
 			log.WithField("scanner", scnr.Name()).Info("Starting scanner")
 
-			findings, err := scnr.Scan(ctx, sourceDir)
-			duration := time.Since(startTime)
+			var findings []*pb.Finding
+			var skipped, incomplete string
+			var err error
+			if hs, ok := scnr.(scanners.HistoryScanner); ok && history != nil && scnr.ScanType() == pb.ScanType_SECRETS {
+				findings, incomplete, err = runHistoryScan(ctx, scnr, hs, sourceDir, changes, history)
+			} else {
+				findings, skipped, err = runScanner(ctx, scnr, sourceDir, changes)
+			}
+			endTime := time.Now()
+			duration := endTime.Sub(startTime)
 
 			results[idx] = &scanners.Result{
 				Findings:    findings,
 				ScanType:    scnr.ScanType(),
 				ScannerName: scnr.Name(),
+				Version:     scannerVersion(ctx, scnr),
+				StartTime:   startTime,
+				EndTime:     endTime,
 				Error:       err,
+				Skipped:     skipped,
+				Incomplete:  incomplete,
 			}
 
-			if err != nil {
+			if skipped != "" {
+				log.WithFields(log.Fields{
+					"scanner": scnr.Name(),
+					"reason":  skipped,
+				}).Info("Scanner skipped")
+			} else if err != nil {
 				log.WithFields(log.Fields{
 					"scanner":  scnr.Name(),
 					"duration": duration,
 				}).WithError(err).Error("Scanner failed")
+			} else if incomplete != "" {
+				log.WithFields(log.Fields{
+					"scanner":  scnr.Name(),
+					"findings": len(findings),
+					"duration": duration,
+					"reason":   incomplete,
+				}).Warn("Scanner completed with partial results")
 			} else {
 				log.WithFields(log.Fields{
 					"scanner":  scnr.Name(),
@@ -243,6 +830,381 @@ func runScannersParallel(ctx context.Context, scannerList []scanners.Scanner, so
 	return results
 }
 
+// runScanner runs a scanner, limited to the change set on incremental scans.
+// It returns a reason instead of running when the changes cannot affect the
+// scanner's results.
+func runScanner(ctx context.Context, scnr scanners.Scanner, sourceDir string, changes *changeset.ChangeSet) ([]*pb.Finding, string, error) {
+	if changes == nil {
+		findings, err := scnr.Scan(ctx, sourceDir)
+		return findings, "", err
+	}
+
+	switch {
+	case scnr.ScanType() == pb.ScanType_SCA:
+		if !changes.DependenciesChanged() {
+			return nil, "no dependency manifest or lockfile changed", nil
+		}
+		findings, err := scnr.Scan(ctx, sourceDir)
+		return findings, "", err
+
+	case scansChangedFiles(scnr.ScanType()):
+		if len(changes.Files) == 0 {
+			return nil, "no files added or modified", nil
+		}
+
+		var findings []*pb.Finding
+		var err error
+		if targeted, ok := scnr.(scanners.TargetedScanner); ok && len(changes.Files) <= maxTargetedFiles {
+			findings, err = targeted.ScanFiles(ctx, sourceDir, changes.Files)
+		} else {
+			findings, err = scnr.Scan(ctx, sourceDir)
+		}
+		if err != nil {
+			return nil, "", err
+		}
+		return changes.Filter(findings, sourceDir), "", nil
+
+	default:
+		findings, err := scnr.Scan(ctx, sourceDir)
+		return findings, "", err
+	}
+}
+
+// historyMode configures secrets scanning of the git history
+type historyMode struct {
+	reserve time.Duration // Time kept for the stages after scanning
+}
+
+// historyReserveFraction keeps 1/5 of SCAN_TIMEOUT for the stages after a
+// git history scan (post-processing, reports and upload)
+const historyReserveFraction = 5
+
+// runHistoryScan scans the working tree as usual, then the git history,
+// limited to commits after the merge base on incremental scans. History
+// findings of secrets still in the tree are dropped, so those keep their
+// snippets and blame attribution. The history scan stops early to leave
+// history.reserve of the scan deadline for later stages; findings reported
+// until then are kept and the reason is returned.
+func runHistoryScan(ctx context.Context, scnr scanners.Scanner, hs scanners.HistoryScanner, repoDir string, changes *changeset.ChangeSet, history *historyMode) ([]*pb.Finding, string, error) {
+	current, _, err := runScanner(ctx, scnr, repoDir, changes)
+	if err != nil {
+		return nil, "", err
+	}
+
+	var opts scanners.HistoryOptions
+	if changes != nil {
+		opts.SinceCommit = changes.MergeBase
+	}
+
+	budgetCtx := ctx
+	if deadline, ok := ctx.Deadline(); ok {
+		var cancel context.CancelFunc
+		budgetCtx, cancel = context.WithDeadline(ctx, deadline.Add(-history.reserve))
+		defer cancel()
+	}
+
+	past, err := hs.ScanHistory(budgetCtx, repoDir, opts)
+	findings := append(current, pastOnly(current, past, repoDir)...)
+	if err != nil && budgetCtx.Err() != nil && ctx.Err() == nil {
+		return findings, "git history scan stopped at its time budget; the remaining history was not scanned", nil
+	}
+	if err != nil {
+		return nil, "", err
+	}
+	return findings, "", nil
+}
+
+// pastOnly returns the history findings whose secret is not also reported
+// in the working tree, matched by title, path and redacted snippet
+func pastOnly(current, past []*pb.Finding, repoDir string) []*pb.Finding {
+	key := func(f *pb.Finding) string {
+		path := f.FilePath
+		if rel, err := filepath.Rel(repoDir, path); err == nil && filepath.IsAbs(path) {
+			path = rel
+		}
+		return f.Title + "\x00" + filepath.ToSlash(path) + "\x00" + f.CodeSnippet
+	}
+
+	inTree := make(map[string]bool, len(current))
+	for _, f := range current {
+		inTree[key(f)] = true
+	}
+	var kept []*pb.Finding
+	for _, f := range past {
+		if !inTree[key(f)] {
+			kept = append(kept, f)
+		}
+	}
+	return kept
+}
+
+// scansChangedFiles reports whether incremental scans of a scan type are
+// limited to changed lines
+func scansChangedFiles(scanType pb.ScanType) bool {
+	return scanType == pb.ScanType_SAST || scanType == pb.ScanType_SECRETS
+}
+
+// applySeverityOverrides applies the severity override table to scanner results
+func applySeverityOverrides(table *overrides.Table, results []*scanners.Result) {
+	total := 0
+	for _, result := range results {
+		if result.Error != nil {
+			continue
+		}
+		total += table.Apply(result.ScannerName, result.Findings)
+	}
+
+	log.WithFields(log.Fields{
+		"overrides":  len(table.Overrides),
+		"overridden": total,
+	}).Info("Applied severity overrides")
+}
+
+// enrichFindings enriches findings from the configured EPSS and KEV files.
+// Findings are still enriched with CVSS data and a risk score when a data
+// file cannot be loaded.
+func enrichFindings(cfg *config.Config, results []*scanners.Result) error {
+	enricher := enrich.New()
+
+	var errs []error
+	if cfg.EPSSDataFile != "" {
+		if err := enricher.LoadEPSS(cfg.EPSSDataFile); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if cfg.KEVDataFile != "" {
+		if err := enricher.LoadKEV(cfg.KEVDataFile); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	var total enrich.Stats
+	for _, result := range results {
+		if result.Error != nil {
+			continue
+		}
+		stats := enricher.Enrich(result.Findings)
+		total.Enriched += stats.Enriched
+		total.EPSS += stats.EPSS
+		total.KEV += stats.KEV
+		total.Escalated += stats.Escalated
+	}
+
+	if total.Enriched > 0 {
+		log.WithFields(log.Fields{
+			"enriched":  total.Enriched,
+			"epss":      total.EPSS,
+			"kev":       total.KEV,
+			"escalated": total.Escalated,
+		}).Info("Enriched findings")
+	}
+
+	return errors.Join(errs...)
+}
+
+// fillSnippets replaces finding snippets with the matched lines and their
+// context read from the workspace
+func fillSnippets(cfg *config.Config, results []*scanners.Result) {
+	extractor := snippet.New(cfg.WorkDir, cfg.SnippetContextLines, cfg.SnippetMaxBytes)
+
+	var total snippet.Stats
+	for _, result := range results {
+		if result.Error != nil {
+			continue
+		}
+		stats := extractor.Fill(result.Findings)
+		total.Filled += stats.Filled
+		total.Located += stats.Located
+		total.Skipped += stats.Skipped
+	}
+
+	log.WithFields(log.Fields{
+		"filled":  total.Filled,
+		"located": total.Located,
+		"skipped": total.Skipped,
+	}).Info("Extracted code snippets")
+}
+
+// attributeFindings adds git blame details to findings. Findings of all
+// scanners are attributed together so each file is blamed once.
+func attributeFindings(ctx context.Context, cfg *config.Config, results []*scanners.Result) {
+	var all []*pb.Finding
+	for _, result := range results {
+		if result.Error == nil {
+			all = append(all, result.Findings...)
+		}
+	}
+
+	total := blame.New(cfg.WorkDir).Attribute(ctx, all)
+
+	log.WithFields(log.Fields{
+		"files":      total.Files,
+		"attributed": total.Attributed,
+		"truncated":  total.Truncated,
+		"failed":     total.Failed,
+	}).Info("Attributed findings with git blame")
+}
+
+// applySuppressions applies the repository's suppression file to scanner
+// results and returns findings reporting expired suppressions
+func applySuppressions(cfg *config.Config, results []*scanners.Result) ([]*pb.Finding, error) {
+	mode, err := suppress.ParseMode(cfg.SuppressionMode)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := suppress.Load(filepath.Join(cfg.WorkDir, suppress.FileName))
+	if err != nil || file == nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	total := 0
+	for _, result := range results {
+		if result.Error != nil {
+			continue
+		}
+		var suppressed int
+		result.Findings, suppressed = file.Apply(result.Findings, cfg.WorkDir, mode, now)
+		total += suppressed
+	}
+
+	expired := file.ExpiredFindings(now)
+	log.WithFields(log.Fields{
+		"entries":    len(file.Suppressions),
+		"suppressed": total,
+		"expired":    len(expired),
+		"mode":       mode,
+	}).Info("Applied suppressions")
+
+	return expired, nil
+}
+
+// compareBaseline fetches the baseline scan's findings, writes a summary of
+// new and fixed findings to the results directory and returns the new ones
+func compareBaseline(ctx context.Context, cfg *config.Config, orchClient scanSink, results []*scanners.Result, current []*pb.Finding, changes *changeset.ChangeSet) ([]*pb.Finding, error) {
+	log.WithField("baseline_scan_id", cfg.BaselineScanID).Info("Comparing findings with baseline scan")
+
+	baseline, err := orchClient.GetFindings(ctx, cfg.BaselineScanID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch baseline findings: %w", err)
+	}
+
+	// Only scan types that ran successfully can have fixed findings, and on
+	// incremental scans only in the files that were scanned
+	scanned := make(map[pb.ScanType]bool)
+	for _, result := range results {
+		if result.Error == nil && result.Skipped == "" {
+			scanned[result.ScanType] = true
+		}
+	}
+	inScope := func(f *pb.Finding) bool {
+		if !scanned[f.ScanType] {
+			return false
+		}
+		if changes == nil || !scansChangedFiles(f.ScanType) {
+			return true
+		}
+		return changes.Changed(findings.NormalizePath(f.FilePath, cfg.WorkDir))
+	}
+
+	diff := findings.Compare(current, baseline, cfg.WorkDir, inScope)
+	summary := report.NewBaselineSummary(cfg.ScanID.String(), cfg.BaselineScanID.String(), cfg.WorkDir, diff)
+
+	log.WithFields(log.Fields{
+		"baseline_scan_id": cfg.BaselineScanID,
+		"new":              summary.New,
+		"unchanged":        summary.Unchanged,
+		"fixed":            summary.Fixed,
+	}).Info("Baseline comparison complete")
+
+	path, err := report.WriteBaselineSummary(cfg.ResultsDir, summary)
+	if err != nil {
+		return nil, err
+	}
+	log.WithField("path", path).Info("Baseline summary written")
+
+	return diff.New, nil
+}
+
+// generateSBOMs writes the requested SBOM documents to the results directory
+// and uploads them when a presigned URL is configured
+func generateSBOMs(ctx context.Context, cfg *config.Config) error {
+	collector := sbom.NewCollector()
+	inventory, err := collector.Collect(ctx, cfg.WorkDir)
+	if err != nil {
+		return err
+	}
+
+	meta := sbom.Metadata{
+		ScanID:      cfg.ScanID.String(),
+		ProjectID:   cfg.ProjectID.String(),
+		GitURL:      cfg.GitURL,
+		GitCommit:   cfg.GitCommit,
+		ToolVersion: version,
+	}
+	uploadURLs := map[sbom.Format]string{
+		sbom.FormatCycloneDX: cfg.SBOMCycloneDXUploadURL,
+		sbom.FormatSPDX:      cfg.SBOMSPDXUploadURL,
+	}
+	httpClient := &http.Client{Timeout: cfg.DownloadTimeout}
+
+	if err := os.MkdirAll(cfg.ResultsDir, 0755); err != nil {
+		return fmt.Errorf("failed to create results directory: %w", err)
+	}
+
+	var errs []error
+	for _, name := range cfg.SBOMFormats {
+		format, err := sbom.ParseFormat(name)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		data, err := sbom.Render(inventory, meta, format)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		path := filepath.Join(cfg.ResultsDir, format.FileName())
+		if err := os.WriteFile(path, data, 0644); err != nil {
+			errs = append(errs, fmt.Errorf("failed to write %s SBOM: %w", format, err))
+			continue
+		}
+		log.WithFields(log.Fields{
+			"format":     format,
+			"path":       path,
+			"components": len(inventory.Components),
+		}).Info("SBOM written")
+
+		if uploadURL := uploadURLs[format]; uploadURL != "" {
+			if err := sbom.Upload(ctx, httpClient, uploadURL, data, format.ContentType()); err != nil {
+				errs = append(errs, fmt.Errorf("failed to upload %s SBOM: %w", format, err))
+				continue
+			}
+			log.WithField("format", format).Info("SBOM uploaded")
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// scannerVersion returns the tool version of scanners that can report it
+func scannerVersion(ctx context.Context, scnr scanners.Scanner) string {
+	versioner, ok := scnr.(scanners.Versioner)
+	if !ok {
+		return ""
+	}
+
+	version, err := versioner.Version(ctx)
+	if err != nil {
+		log.WithError(err).WithField("scanner", scnr.Name()).Debug("Failed to determine scanner version")
+		return ""
+	}
+	return version
+}
+
 func setLogLevel(level string) {
 	switch level {
 	case "debug":
@@ -5,8 +5,11 @@ go 1.24.0
 require (
 	github.com/cloud-scan/cloudscan-orchestrator v0.0.0-20260202165107-688adaaf5528
 	github.com/google/uuid v1.6.0
+	github.com/klauspost/compress v1.18.0
 	github.com/sirupsen/logrus v1.9.3
+	github.com/ulikunitz/xz v0.5.15
 	google.golang.org/grpc v1.78.0
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
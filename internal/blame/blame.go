@@ -0,0 +1,209 @@
+// Package blame attributes findings to the commits and authors of the lines
+// they were reported on, using git blame on the cloned repository.
+package blame
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	pb "github.com/cloud-scan/cloudscan-orchestrator/generated/proto"
+	"github.com/cloud-scan/cloudscan-runner/internal/findings"
+	log "github.com/sirupsen/logrus"
+)
+
+// maxParallel bounds the number of files blamed concurrently
+const maxParallel = 8
+
+// Attributor runs git blame in a repository
+type Attributor struct {
+	repoDir string
+	shallow bool
+	logger  *log.Entry
+}
+
+// Stats summarizes an attribution pass
+type Stats struct {
+	Files      int // Files blamed
+	Attributed int // Findings given blame details
+	Truncated  int // Findings whose line predates the shallow history
+	Failed     int // Files that could not be blamed (e.g. untracked)
+}
+
+// New creates an attributor for the repository at repoDir
+func New(repoDir string) *Attributor {
+	return &Attributor{
+		repoDir: repoDir,
+		logger:  log.WithField("component", "blame"),
+	}
+}
+
+// Attribute adds blame details to every finding with a file and line in the
+// repository. Findings are grouped by file and each file is blamed once for
+// all of its finding lines, with several files blamed in parallel.
+func (a *Attributor) Attribute(ctx context.Context, results []*pb.Finding) Stats {
+	byFile := make(map[string][]*pb.Finding)
+	for _, f := range results {
+		// Findings from past commits already name their commit
+		if f.FilePath == "" || f.LineNumber <= 0 || findings.FromHistory(f) {
+			continue
+		}
+		path := findings.NormalizePath(f.FilePath, a.repoDir)
+		if filepath.IsAbs(path) || path == ".." || strings.HasPrefix(path, "../") {
+			continue
+		}
+		byFile[path] = append(byFile[path], f)
+	}
+
+	paths := make([]string, 0, len(byFile))
+	for path := range byFile {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	// Root commits are reported as boundaries too; they only mean truncated
+	// history in a shallow clone
+	out, err := exec.CommandContext(ctx, "git", "-C", a.repoDir, "rev-parse", "--is-shallow-repository").Output()
+	a.shallow = err == nil && strings.TrimSpace(string(out)) == "true"
+
+	var (
+		mu    sync.Mutex
+		wg    sync.WaitGroup
+		stats Stats
+		sem   = make(chan struct{}, maxParallel)
+	)
+	for _, path := range paths {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(path string, fileFindings []*pb.Finding) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			attributed, truncated, err := a.attributeFile(ctx, path, fileFindings)
+
+			mu.Lock()
+			defer mu.Unlock()
+			stats.Files++
+			if err != nil {
+				stats.Failed++
+				a.logger.WithError(err).WithField("file", path).Debug("Failed to blame file")
+				return
+			}
+			stats.Attributed += attributed
+			stats.Truncated += truncated
+		}(path, byFile[path])
+	}
+	wg.Wait()
+
+	return stats
+}
+
+// attributeFile blames the finding lines of one file. Two passes run: a
+// plain blame for the commit that last changed each line, and one ignoring
+// whitespace, moves and copies for the commit that introduced its content.
+func (a *Attributor) attributeFile(ctx context.Context, path string, fileFindings []*pb.Finding) (int, int, error) {
+	lineCount, err := countLines(filepath.Join(a.repoDir, filepath.FromSlash(path)))
+	if err != nil {
+		return 0, 0, err
+	}
+
+	var lines []int
+	seen := make(map[int]bool)
+	for _, f := range fileFindings {
+		line := int(f.LineNumber)
+		if line <= lineCount && !seen[line] {
+			seen[line] = true
+			lines = append(lines, line)
+		}
+	}
+	if len(lines) == 0 {
+		return 0, 0, nil
+	}
+	sort.Ints(lines)
+
+	changed, err := a.blame(ctx, path, lines)
+	if err != nil {
+		return 0, 0, err
+	}
+	introduced, err := a.blame(ctx, path, lines, "-w", "-M", "-C")
+	if err != nil {
+		return 0, 0, err
+	}
+
+	attributed, truncated := 0, 0
+	for _, f := range fileFindings {
+		last, ok := changed[int(f.LineNumber)]
+		if !ok || last.uncommitted() {
+			continue
+		}
+		findings.SetDetail(f, findings.DetailBlameCommit, last.SHA)
+		findings.SetDetail(f, findings.DetailBlameAuthor, last.author())
+		findings.SetDetail(f, findings.DetailBlameDate, last.date())
+
+		isTruncated := a.shallow && last.Boundary
+		if first, ok := introduced[int(f.LineNumber)]; ok && !first.uncommitted() {
+			findings.SetDetail(f, findings.DetailIntroducedCommit, first.SHA)
+			findings.SetDetail(f, findings.DetailIntroducedAuthor, first.author())
+			findings.SetDetail(f, findings.DetailIntroducedDate, first.date())
+			isTruncated = isTruncated || a.shallow && first.Boundary
+		}
+		if isTruncated {
+			// The line is at least as old as the oldest commit cloned
+			findings.SetDetail(f, findings.DetailBlameTruncated, "true")
+			truncated++
+		}
+		attributed++
+	}
+	return attributed, truncated, nil
+}
+
+// blame runs git blame on the given lines of a file at HEAD
+func (a *Attributor) blame(ctx context.Context, path string, lines []int, flags ...string) (map[int]*Commit, error) {
+	args := []string{"-C", a.repoDir, "blame", "--porcelain"}
+	args = append(args, flags...)
+	for _, r := range ranges(lines) {
+		args = append(args, "-L", fmt.Sprintf("%d,%d", r[0], r[1]))
+	}
+	args = append(args, "HEAD", "--", path)
+
+	cmd := exec.CommandContext(ctx, "git", args...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("git blame: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return parsePorcelain(out)
+}
+
+// ranges merges sorted line numbers into contiguous [start, end] ranges
+func ranges(lines []int) [][2]int {
+	var out [][2]int
+	for _, line := range lines {
+		if n := len(out); n > 0 && out[n-1][1]+1 >= line {
+			out[n-1][1] = line
+			continue
+		}
+		out = append(out, [2]int{line, line})
+	}
+	return out
+}
+
+// countLines returns the number of lines in a file
+func countLines(path string) (int, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+	n := bytes.Count(data, []byte{'\n'})
+	if len(data) > 0 && data[len(data)-1] != '\n' {
+		n++
+	}
+	return n, nil
+}
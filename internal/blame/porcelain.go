@@ -0,0 +1,104 @@
+package blame
+
+import (
+	"bufio"
+	"bytes"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Commit is the blame information of a commit
+type Commit struct {
+	SHA        string
+	Author     string
+	AuthorMail string
+	AuthorTime time.Time
+	Summary    string
+	Boundary   bool // Root or oldest cloned commit; the line may be older
+}
+
+// uncommitted reports the placeholder git uses for uncommitted lines
+func (c *Commit) uncommitted() bool {
+	return strings.Trim(c.SHA, "0") == ""
+}
+
+// author formats the author as "Name <email>"
+func (c *Commit) author() string {
+	if c.AuthorMail == "" {
+		return c.Author
+	}
+	return c.Author + " " + c.AuthorMail
+}
+
+// date formats the author date as RFC 3339
+func (c *Commit) date() string {
+	if c.AuthorTime.IsZero() {
+		return ""
+	}
+	return c.AuthorTime.UTC().Format(time.RFC3339)
+}
+
+// parsePorcelain maps final line numbers to commits from git blame
+// --porcelain output. Commit headers are printed only the first time a
+// commit appears, so commits are shared between lines.
+func parsePorcelain(out []byte) (map[int]*Commit, error) {
+	lines := make(map[int]*Commit)
+	commits := make(map[string]*Commit)
+
+	var current *Commit
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(line, "\t") {
+			// Line content ends the entry
+			current = nil
+			continue
+		}
+
+		if current == nil {
+			sha, final, ok := parseLineHeader(line)
+			if !ok {
+				continue
+			}
+			current = commits[sha]
+			if current == nil {
+				current = &Commit{SHA: sha}
+				commits[sha] = current
+			}
+			lines[final] = current
+			continue
+		}
+
+		key, value, _ := strings.Cut(line, " ")
+		switch key {
+		case "author":
+			current.Author = value
+		case "author-mail":
+			current.AuthorMail = value
+		case "author-time":
+			if secs, err := strconv.ParseInt(value, 10, 64); err == nil {
+				current.AuthorTime = time.Unix(secs, 0)
+			}
+		case "summary":
+			current.Summary = value
+		case "boundary":
+			current.Boundary = true
+		}
+	}
+	return lines, scanner.Err()
+}
+
+// parseLineHeader parses "<sha> <orig-line> <final-line> [<count>]"
+func parseLineHeader(line string) (string, int, bool) {
+	fields := strings.Fields(line)
+	if len(fields) < 3 || len(fields[0]) != 40 && len(fields[0]) != 64 {
+		return "", 0, false
+	}
+	final, err := strconv.Atoi(fields[2])
+	if err != nil {
+		return "", 0, false
+	}
+	return fields[0], final, true
+}
@@ -0,0 +1,246 @@
+// Package changeset computes the files and lines a pull request changes
+// relative to the merge base with its target branch.
+package changeset
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+
+	pb "github.com/cloud-scan/cloudscan-orchestrator/generated/proto"
+	"github.com/cloud-scan/cloudscan-runner/internal/findings"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// deepenStep is the number of commits fetched per attempt to find the merge base
+	deepenStep = 50
+	// maxDeepen bounds deepening before falling back to fetching full history
+	maxDeepen = 10
+)
+
+var hunkHeader = regexp.MustCompile(`^@@ -\d+(?:,\d+)? \+(\d+)(?:,(\d+))? @@`)
+
+// Base identifies the revision a pull request is compared against
+type Base struct {
+	Commit string // Base commit SHA (takes precedence over Branch)
+	Branch string // Target branch name
+}
+
+// Range is an inclusive range of line numbers in the head revision
+type Range struct {
+	Start int
+	End   int
+}
+
+// ChangeSet lists what changed between the merge base and HEAD
+type ChangeSet struct {
+	MergeBase string
+	Files     []string // Added or modified files, relative to the repository root
+	Deleted   []string // Removed files, relative to the repository root
+
+	changed map[string]bool
+	hunks   map[string][]Range
+}
+
+// Compute fetches enough history of the clone in repoDir to find the merge
+// base of HEAD and base, and returns the changes made since
+func Compute(ctx context.Context, repoDir string, base Base) (*ChangeSet, error) {
+	logger := log.WithField("component", "changeset")
+
+	if base.Commit == "" && base.Branch == "" {
+		return nil, fmt.Errorf("no base commit or branch given")
+	}
+
+	refspec, baseRev := base.Commit, base.Commit
+	if base.Commit == "" {
+		baseRev = "refs/remotes/origin/" + base.Branch
+		refspec = "+refs/heads/" + base.Branch + ":" + baseRev
+	}
+
+	logger.WithFields(log.Fields{
+		"base_commit": base.Commit,
+		"base_branch": base.Branch,
+	}).Info("Fetching pull request base")
+
+	if _, err := git(ctx, repoDir, "fetch", "--no-tags", "--depth="+strconv.Itoa(deepenStep), "origin", refspec); err != nil {
+		return nil, fmt.Errorf("failed to fetch base: %w", err)
+	}
+
+	mergeBase, err := findMergeBase(ctx, repoDir, refspec, baseRev)
+	if err != nil {
+		return nil, err
+	}
+
+	cs := &ChangeSet{
+		MergeBase: mergeBase,
+		changed:   make(map[string]bool),
+		hunks:     make(map[string][]Range),
+	}
+
+	if err := cs.loadFiles(ctx, repoDir); err != nil {
+		return nil, err
+	}
+	if err := cs.loadHunks(ctx, repoDir); err != nil {
+		return nil, err
+	}
+
+	logger.WithFields(log.Fields{
+		"merge_base": mergeBase,
+		"changed":    len(cs.Files),
+		"deleted":    len(cs.Deleted),
+	}).Info("Change set computed")
+
+	return cs, nil
+}
+
+// findMergeBase deepens the shallow history until HEAD and baseRev share an
+// ancestor, fetching full history as a last resort
+func findMergeBase(ctx context.Context, repoDir, refspec, baseRev string) (string, error) {
+	for attempt := 0; ; attempt++ {
+		out, err := git(ctx, repoDir, "merge-base", "HEAD", baseRev)
+		if err == nil {
+			return strings.TrimSpace(string(out)), nil
+		}
+
+		switch {
+		case attempt < maxDeepen:
+			_, err = git(ctx, repoDir, "fetch", "--no-tags", "--deepen="+strconv.Itoa(deepenStep), "origin", refspec)
+		case attempt == maxDeepen:
+			_, err = git(ctx, repoDir, "fetch", "--no-tags", "--unshallow", "origin", refspec)
+		default:
+			return "", fmt.Errorf("no merge base between HEAD and %s", baseRev)
+		}
+		if err != nil {
+			return "", fmt.Errorf("failed to fetch history: %w", err)
+		}
+	}
+}
+
+// loadFiles lists files changed since the merge base
+func (c *ChangeSet) loadFiles(ctx context.Context, repoDir string) error {
+	out, err := git(ctx, repoDir, "diff", "--name-status", "-z", "--no-renames", c.MergeBase, "HEAD")
+	if err != nil {
+		return fmt.Errorf("failed to list changed files: %w", err)
+	}
+
+	fields := strings.Split(strings.TrimSuffix(string(out), "\x00"), "\x00")
+	for i := 0; i+1 < len(fields); i += 2 {
+		status, path := fields[i], fields[i+1]
+		c.changed[path] = true
+		if strings.HasPrefix(status, "D") {
+			c.Deleted = append(c.Deleted, path)
+		} else {
+			c.Files = append(c.Files, path)
+		}
+	}
+	return nil
+}
+
+// loadHunks records the head-side line ranges of every change
+func (c *ChangeSet) loadHunks(ctx context.Context, repoDir string) error {
+	out, err := git(ctx, repoDir, "-c", "core.quotePath=false", "diff", "-U0", "--no-color", "--no-ext-diff", "--no-renames", c.MergeBase, "HEAD")
+	if err != nil {
+		return fmt.Errorf("failed to diff changes: %w", err)
+	}
+
+	var current string
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case strings.HasPrefix(line, "+++ "):
+			current = diffPath(strings.TrimPrefix(line, "+++ "))
+		case strings.HasPrefix(line, "@@ ") && current != "":
+			m := hunkHeader.FindStringSubmatch(line)
+			if m == nil {
+				continue
+			}
+			start, _ := strconv.Atoi(m[1])
+			count := 1
+			if m[2] != "" {
+				count, _ = strconv.Atoi(m[2])
+			}
+			if count > 0 {
+				c.hunks[current] = append(c.hunks[current], Range{Start: start, End: start + count - 1})
+			}
+		}
+	}
+	return scanner.Err()
+}
+
+// diffPath extracts the repository path from a "+++" diff header
+func diffPath(header string) string {
+	if header == "/dev/null" {
+		return ""
+	}
+	if strings.HasPrefix(header, `"`) {
+		if unquoted, err := strconv.Unquote(header); err == nil {
+			header = unquoted
+		}
+	}
+	return strings.TrimPrefix(header, "b/")
+}
+
+// Changed reports whether a file was added, modified or deleted
+func (c *ChangeSet) Changed(path string) bool {
+	return c.changed[path]
+}
+
+// InChangedLines reports whether a line of a file was added or modified.
+// Findings without a line, and files whose changes are not line-addressable
+// (binary or mode-only changes), count as changed when the file changed.
+func (c *ChangeSet) InChangedLines(path string, line int) bool {
+	if !c.changed[path] {
+		return false
+	}
+	ranges, ok := c.hunks[path]
+	if !ok || line <= 0 {
+		return true
+	}
+	for _, r := range ranges {
+		if line >= r.Start && line <= r.End {
+			return true
+		}
+	}
+	return false
+}
+
+// Filter keeps the findings located in changed lines
+func (c *ChangeSet) Filter(results []*pb.Finding, sourceDir string) []*pb.Finding {
+	kept := make([]*pb.Finding, 0, len(results))
+	for _, f := range results {
+		if c.InChangedLines(findings.NormalizePath(f.FilePath, sourceDir), int(f.LineNumber)) {
+			kept = append(kept, f)
+		}
+	}
+	return kept
+}
+
+// DependenciesChanged reports whether any dependency manifest or lockfile changed
+func (c *ChangeSet) DependenciesChanged() bool {
+	for path := range c.changed {
+		if IsDependencyFile(path) {
+			return true
+		}
+	}
+	return false
+}
+
+// git runs a git command in repoDir and returns its stdout
+func git(ctx context.Context, repoDir string, args ...string) ([]byte, error) {
+	cmd := exec.CommandContext(ctx, "git", append([]string{"-C", repoDir}, args...)...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("git %s: %w: %s", args[0], err, strings.TrimSpace(stderr.String()))
+	}
+	return out, nil
+}
@@ -0,0 +1,40 @@
+package changeset
+
+import (
+	"path"
+	"strings"
+)
+
+// dependencyFiles are manifest and lockfile names understood by SCA scanners
+var dependencyFiles = map[string]bool{
+	"go.mod": true, "go.sum": true,
+	"package.json": true, "package-lock.json": true, "npm-shrinkwrap.json": true,
+	"yarn.lock": true, "pnpm-lock.yaml": true, "bun.lockb": true,
+	"requirements.txt": true, "Pipfile": true, "Pipfile.lock": true,
+	"poetry.lock": true, "pyproject.toml": true, "setup.py": true, "setup.cfg": true, "uv.lock": true,
+	"Gemfile": true, "Gemfile.lock": true, "gems.rb": true, "gems.locked": true,
+	"pom.xml": true, "build.gradle": true, "build.gradle.kts": true, "gradle.lockfile": true,
+	"Cargo.toml": true, "Cargo.lock": true,
+	"composer.json": true, "composer.lock": true,
+	"packages.config": true, "packages.lock.json": true, "Directory.Packages.props": true,
+	"mix.exs": true, "mix.lock": true,
+	"Podfile": true, "Podfile.lock": true, "Package.swift": true, "Package.resolved": true,
+	"pubspec.yaml": true, "pubspec.lock": true,
+	"conanfile.txt": true, "conan.lock": true,
+}
+
+// IsDependencyFile reports whether path is a dependency manifest or lockfile
+func IsDependencyFile(p string) bool {
+	name := path.Base(p)
+	if dependencyFiles[name] {
+		return true
+	}
+	// requirements-dev.txt, requirements/prod.txt and .NET project files
+	if strings.HasPrefix(name, "requirements") && strings.HasSuffix(name, ".txt") {
+		return true
+	}
+	if path.Base(path.Dir(p)) == "requirements" && strings.HasSuffix(name, ".txt") {
+		return true
+	}
+	return strings.HasSuffix(name, ".csproj") || strings.HasSuffix(name, ".fsproj") || strings.HasSuffix(name, ".vbproj")
+}
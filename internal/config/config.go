@@ -1,7 +1,9 @@
 package config
 
 import (
+	"encoding/hex"
 	"fmt"
+	"net/url"
 	"os"
 	"strconv"
 	"strings"
@@ -19,24 +21,82 @@ type Config struct {
 	OrganizationID     uuid.UUID
 	ProjectID          uuid.UUID
 	ScanTypes          []string
+	ScannerSelection   string // "best" (one scanner per type) or "all"
+	ScannerManifestDir string // Directory of declarative scanner manifests
+	BaselineScanID     uuid.UUID // Earlier scan to diff against; only new findings are uploaded
+	SuppressionMode    string    // "drop" or "info" for findings matched by .cloudscanignore
+	PolicyFile         string    // Optional policy gate rules (YAML)
+	SeverityOverridesFile string // Optional severity override table (YAML)
+	EPSSDataFile          string // Optional FIRST EPSS CSV (may be gzipped)
+	KEVDataFile           string // Optional CISA KEV catalog JSON (may be gzipped)
 
 	// Repository info
-	GitURL    string
-	GitBranch string
-	GitCommit string
+	GitURL        string
+	GitBranch     string // Branch, tag or ref such as refs/pull/123/head
+	GitCommit     string
+	GitSubmodules bool   // Initialize submodules recursively
+	GitLFS        bool   // Fetch Git LFS objects
+
+	// Git credentials; secrets are read from mounted files and never logged
+	GitUsername       string
+	GitPassword       string // Password, access token or GitHub App installation token
+	GitSSHKeyFile     string
+	GitKnownHostsFile string
+
+	// Pull request base; when set, only changes since the merge base are scanned
+	BaseCommit string
+	BaseBranch string
+
+	// Blame attribution of findings (Git sources only)
+	BlameEnabled bool
+	BlameDepth   int // Commits of history cloned for blame; 0 clones all
+
+	// Secrets scanning of the git history (Git sources only)
+	SecretsHistory      bool
+	SecretsHistorySince time.Time // Only clone and scan commits since then; zero means all
 
 	// Service endpoints
 	OrchestratorEndpoint string
 	StorageEndpoint      string
 	SourceDownloadURL    string  // Presigned URL to download source archive
+	SourceSHA256         string  // Optional expected SHA-256 of the source archive
+	SBOMDownloadURL      string  // Presigned URL to download an SBOM to scan instead of source
+	SourcePath           string  // Directory of source code already on disk
+	SourceCopy           bool    // Copy SourcePath into WorkDir instead of scanning it in place
+
+	// Orchestrator connection security; credentials are read from mounted
+	// files, which are re-read when rotated
+	OrchestratorTLS        bool
+	OrchestratorCAFile     string // CA bundle verifying the orchestrator; system roots when empty
+	OrchestratorServerName string // Name verified in the orchestrator certificate
+	OrchestratorCertFile   string // Client certificate for mutual TLS
+	OrchestratorKeyFile    string
+	OrchestratorTokenFile  string // Bearer token, e.g. a projected service account token
 
 	// Working directories
 	WorkDir    string
 	ResultsDir string
 
+	// SBOM generation
+	SBOMFormats            []string // SBOM formats to produce ("cyclonedx", "spdx"); empty disables
+	SBOMCycloneDXUploadURL string   // Optional presigned PUT URL for the CycloneDX SBOM
+	SBOMSPDXUploadURL      string   // Optional presigned PUT URL for the SPDX SBOM
+
+	// Archive extraction limits; zero disables a limit
+	ExtractMaxBytes     int64   // Uncompressed bytes of all files
+	ExtractMaxFiles     int     // Archive entries
+	ExtractMaxFileBytes int64   // Uncompressed bytes of a single file
+	ExtractMaxRatio     float64 // Uncompressed bytes per archive byte
+	ExtractLinks        string  // "skip" or "within" for symlinks and hardlinks
+
+	// Code snippets
+	SnippetContextLines int // Lines of context shown around each finding
+	SnippetMaxBytes     int // Maximum snippet size
+
 	// Timeouts
 	ScanTimeout  time.Duration
 	DownloadTimeout time.Duration
+	DownloadRetries int // Consecutive retries of a failed download request
 
 	// Logging
 	LogLevel string
@@ -84,32 +144,176 @@ func LoadFromEnv() (*Config, error) {
 	if cfg.OrchestratorEndpoint == "" {
 		return nil, fmt.Errorf("ORCHESTRATOR_ENDPOINT environment variable is required")
 	}
+	if err := loadOrchestratorAuth(cfg); err != nil {
+		return nil, err
+	}
 
 	scanTypesStr := os.Getenv("SCAN_TYPES")
 	if scanTypesStr == "" {
 		return nil, fmt.Errorf("SCAN_TYPES environment variable is required")
 	}
 	cfg.ScanTypes = strings.Split(scanTypesStr, ",")
+	cfg.ScannerSelection = getEnv("SCANNER_SELECTION", "best")
+	cfg.ScannerManifestDir = getEnv("SCANNER_MANIFEST_DIR", "/etc/cloudscan/scanners")
+	cfg.SuppressionMode = getEnv("SUPPRESSION_MODE", "drop")
+	cfg.PolicyFile = getEnv("POLICY_FILE", "")
+	cfg.SeverityOverridesFile = getEnv("SEVERITY_OVERRIDES_FILE", "")
+	cfg.EPSSDataFile = getEnv("EPSS_DATA_FILE", "")
+	cfg.KEVDataFile = getEnv("KEV_DATA_FILE", "")
+
+	if baselineStr := os.Getenv("BASELINE_SCAN_ID"); baselineStr != "" {
+		baselineID, err := uuid.Parse(baselineStr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid BASELINE_SCAN_ID: %w", err)
+		}
+		cfg.BaselineScanID = baselineID
+	}
 
 	// Optional fields with defaults
 	cfg.GitURL = getEnv("REPOSITORY_URL", "")  // Changed from GIT_URL to match dispatcher
 	cfg.GitBranch = getEnv("BRANCH", "")       // Changed from GIT_BRANCH to match dispatcher
 	cfg.GitCommit = getEnv("COMMIT_SHA", "")   // Changed from GIT_COMMIT to match dispatcher
+	cfg.BaseCommit = getEnv("BASE_COMMIT", "")  // Optional - incremental pull request scans
+	cfg.BaseBranch = getEnv("BASE_BRANCH", "")  // Optional - incremental pull request scans
 	cfg.StorageEndpoint = getEnv("STORAGE_SERVICE_ENDPOINT", "")  // Match dispatcher
 	cfg.SourceDownloadURL = getEnv("SOURCE_DOWNLOAD_URL", "")  // Optional - only for artifact scans
+	cfg.SBOMDownloadURL = getEnv("SBOM_DOWNLOAD_URL", "")  // Optional - only for SBOM scans
+	cfg.SourceSHA256 = strings.ToLower(getEnv("SOURCE_SHA256", ""))  // Optional - verifies the source archive
+	cfg.SourcePath = getEnv("SOURCE_PATH", "")  // Optional - only for pre-mounted source code
 
-	// Validate: Must have either Git repository OR artifact download URL
+	// Validate: Must have a Git repository, artifact download URL, SBOM download URL or source path
 	hasGitSource := cfg.GitURL != ""
 	hasArtifactSource := cfg.SourceDownloadURL != ""
+	hasSBOMSource := cfg.SBOMDownloadURL != ""
+	hasPathSource := cfg.SourcePath != ""
 
-	if !hasGitSource && !hasArtifactSource {
-		return nil, fmt.Errorf("either REPOSITORY_URL or SOURCE_DOWNLOAD_URL must be provided")
+	if !hasGitSource && !hasArtifactSource && !hasSBOMSource && !hasPathSource {
+		return nil, fmt.Errorf("one of REPOSITORY_URL, SOURCE_DOWNLOAD_URL, SBOM_DOWNLOAD_URL or SOURCE_PATH must be provided")
+	}
+	if hasPathSource {
+		if hasGitSource || hasArtifactSource || hasSBOMSource {
+			return nil, fmt.Errorf("SOURCE_PATH cannot be combined with REPOSITORY_URL, SOURCE_DOWNLOAD_URL or SBOM_DOWNLOAD_URL")
+		}
+		if info, err := os.Stat(cfg.SourcePath); err != nil || !info.IsDir() {
+			return nil, fmt.Errorf("SOURCE_PATH %s is not a directory", cfg.SourcePath)
+		}
+	}
+	if (cfg.BaseCommit != "" || cfg.BaseBranch != "") && !hasGitSource {
+		return nil, fmt.Errorf("BASE_COMMIT and BASE_BRANCH require REPOSITORY_URL")
+	}
+	if cfg.SourceSHA256 != "" {
+		if !hasArtifactSource {
+			return nil, fmt.Errorf("SOURCE_SHA256 requires SOURCE_DOWNLOAD_URL")
+		}
+		if _, err := hex.DecodeString(cfg.SourceSHA256); err != nil || len(cfg.SourceSHA256) != 64 {
+			return nil, fmt.Errorf("invalid SOURCE_SHA256: expected 64 hex characters")
+		}
+	}
+	if hasSBOMSource && (hasArtifactSource || hasGitSource) {
+		return nil, fmt.Errorf("SBOM_DOWNLOAD_URL cannot be combined with SOURCE_DOWNLOAD_URL or REPOSITORY_URL")
 	}
 
 	cfg.WorkDir = getEnv("WORK_DIR", "/workspace")
 	cfg.ResultsDir = getEnv("RESULTS_DIR", "/results")
 	cfg.LogLevel = getEnv("LOG_LEVEL", "info")
 
+	// SBOMs are produced for every scan unless SBOM_FORMATS=none
+	if sbomFormats := getEnv("SBOM_FORMATS", "cyclonedx,spdx"); sbomFormats != "none" {
+		cfg.SBOMFormats = strings.Split(sbomFormats, ",")
+	}
+	cfg.SBOMCycloneDXUploadURL = getEnv("SBOM_CYCLONEDX_UPLOAD_URL", "")
+	cfg.SBOMSPDXUploadURL = getEnv("SBOM_SPDX_UPLOAD_URL", "")
+
+	cfg.SourceCopy, err = strconv.ParseBool(getEnv("SOURCE_COPY", "false"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid SOURCE_COPY: %w", err)
+	}
+
+	cfg.GitSubmodules, err = strconv.ParseBool(getEnv("GIT_SUBMODULES", "false"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid GIT_SUBMODULES: %w", err)
+	}
+	cfg.GitLFS, err = strconv.ParseBool(getEnv("GIT_LFS", "false"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid GIT_LFS: %w", err)
+	}
+
+	if err := loadGitAuth(cfg); err != nil {
+		return nil, err
+	}
+
+	cfg.BlameEnabled, err = strconv.ParseBool(getEnv("BLAME_ENABLED", "false"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid BLAME_ENABLED: %w", err)
+	}
+	blameDepth, err := strconv.Atoi(getEnv("BLAME_DEPTH", "1000"))
+	if err != nil || blameDepth < 0 {
+		log.Warnf("Invalid BLAME_DEPTH, using default: %v", err)
+		blameDepth = 1000
+	}
+	cfg.BlameDepth = blameDepth
+
+	cfg.SecretsHistory, err = strconv.ParseBool(getEnv("SECRETS_HISTORY", "false"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid SECRETS_HISTORY: %w", err)
+	}
+	if since := os.Getenv("SECRETS_HISTORY_SINCE"); since != "" {
+		cfg.SecretsHistorySince, err = time.Parse("2006-01-02", since)
+		if err != nil {
+			return nil, fmt.Errorf("invalid SECRETS_HISTORY_SINCE (expected YYYY-MM-DD): %w", err)
+		}
+	}
+	if cfg.SecretsHistory && !hasGitSource {
+		return nil, fmt.Errorf("SECRETS_HISTORY requires REPOSITORY_URL")
+	}
+
+	extractMaxBytes, err := strconv.ParseInt(getEnv("EXTRACT_MAX_BYTES", "10737418240"), 10, 64)
+	if err != nil || extractMaxBytes < 0 {
+		log.Warnf("Invalid EXTRACT_MAX_BYTES, using default: %v", err)
+		extractMaxBytes = 10737418240
+	}
+	cfg.ExtractMaxBytes = extractMaxBytes
+
+	extractMaxFiles, err := strconv.Atoi(getEnv("EXTRACT_MAX_FILES", "1000000"))
+	if err != nil || extractMaxFiles < 0 {
+		log.Warnf("Invalid EXTRACT_MAX_FILES, using default: %v", err)
+		extractMaxFiles = 1000000
+	}
+	cfg.ExtractMaxFiles = extractMaxFiles
+
+	extractMaxFileBytes, err := strconv.ParseInt(getEnv("EXTRACT_MAX_FILE_BYTES", "1073741824"), 10, 64)
+	if err != nil || extractMaxFileBytes < 0 {
+		log.Warnf("Invalid EXTRACT_MAX_FILE_BYTES, using default: %v", err)
+		extractMaxFileBytes = 1073741824
+	}
+	cfg.ExtractMaxFileBytes = extractMaxFileBytes
+
+	extractMaxRatio, err := strconv.ParseFloat(getEnv("EXTRACT_MAX_RATIO", "200"), 64)
+	if err != nil || extractMaxRatio < 0 {
+		log.Warnf("Invalid EXTRACT_MAX_RATIO, using default: %v", err)
+		extractMaxRatio = 200
+	}
+	cfg.ExtractMaxRatio = extractMaxRatio
+
+	cfg.ExtractLinks = getEnv("EXTRACT_LINKS", "skip")
+	if cfg.ExtractLinks != "skip" && cfg.ExtractLinks != "within" {
+		return nil, fmt.Errorf("invalid EXTRACT_LINKS %q (expected skip or within)", cfg.ExtractLinks)
+	}
+
+	snippetContextLines, err := strconv.Atoi(getEnv("SNIPPET_CONTEXT_LINES", "3"))
+	if err != nil {
+		log.Warnf("Invalid SNIPPET_CONTEXT_LINES, using default: %v", err)
+		snippetContextLines = 3
+	}
+	cfg.SnippetContextLines = snippetContextLines
+
+	snippetMaxBytes, err := strconv.Atoi(getEnv("SNIPPET_MAX_BYTES", "4096"))
+	if err != nil {
+		log.Warnf("Invalid SNIPPET_MAX_BYTES, using default: %v", err)
+		snippetMaxBytes = 4096
+	}
+	cfg.SnippetMaxBytes = snippetMaxBytes
+
 	// Parse timeout values
 	scanTimeoutSec, err := strconv.Atoi(getEnv("SCAN_TIMEOUT", "1800"))
 	if err != nil {
@@ -125,6 +329,13 @@ func LoadFromEnv() (*Config, error) {
 	}
 	cfg.DownloadTimeout = time.Duration(downloadTimeoutSec) * time.Second
 
+	downloadRetries, err := strconv.Atoi(getEnv("DOWNLOAD_RETRIES", "5"))
+	if err != nil || downloadRetries < 0 {
+		log.Warnf("Invalid DOWNLOAD_RETRIES, using default: %v", err)
+		downloadRetries = 5
+	}
+	cfg.DownloadRetries = downloadRetries
+
 	log.WithFields(log.Fields{
 		"scan_id":       cfg.ScanID,
 		"artifact_id":   cfg.SourceArtifactID,
@@ -137,6 +348,150 @@ func LoadFromEnv() (*Config, error) {
 	return cfg, nil
 }
 
+// NewLocal returns the configuration of a local scan of sourceDir, run
+// without an orchestrator. Scan settings start at the job defaults; the
+// scanner manifest, severity override and enrichment files may still be set
+// through their environment variables.
+func NewLocal(sourceDir string) (*Config, error) {
+	info, err := os.Stat(sourceDir)
+	if err != nil || !info.IsDir() {
+		return nil, fmt.Errorf("%s is not a directory", sourceDir)
+	}
+
+	return &Config{
+		ScanID:                uuid.New(),
+		ScanTypes:             []string{"sast", "sca", "secrets", "license"},
+		ScannerSelection:      "best",
+		ScannerManifestDir:    getEnv("SCANNER_MANIFEST_DIR", "/etc/cloudscan/scanners"),
+		SuppressionMode:       "drop",
+		SeverityOverridesFile: getEnv("SEVERITY_OVERRIDES_FILE", ""),
+		EPSSDataFile:          getEnv("EPSS_DATA_FILE", ""),
+		KEVDataFile:           getEnv("KEV_DATA_FILE", ""),
+		SourcePath:            sourceDir,
+		WorkDir:               sourceDir,
+		ExtractLinks:          "skip",
+		SnippetContextLines:   3,
+		SnippetMaxBytes:       4096,
+		ScanTimeout:           30 * time.Minute,
+		DownloadTimeout:       300 * time.Second,
+		DownloadRetries:       5,
+		LogLevel:              "warn",
+	}, nil
+}
+
+// LoadEnvironment returns the runner's environment settings that do not
+// describe a scan: directories, data files and the orchestrator endpoint.
+// Unlike LoadFromEnv it requires no scan variables.
+func LoadEnvironment() (*Config, error) {
+	cfg := &Config{
+		ScannerManifestDir:    getEnv("SCANNER_MANIFEST_DIR", "/etc/cloudscan/scanners"),
+		PolicyFile:            getEnv("POLICY_FILE", ""),
+		SeverityOverridesFile: getEnv("SEVERITY_OVERRIDES_FILE", ""),
+		EPSSDataFile:          getEnv("EPSS_DATA_FILE", ""),
+		KEVDataFile:           getEnv("KEV_DATA_FILE", ""),
+		OrchestratorEndpoint:  getEnv("ORCHESTRATOR_ENDPOINT", ""),
+		WorkDir:               getEnv("WORK_DIR", "/workspace"),
+		ResultsDir:            getEnv("RESULTS_DIR", "/results"),
+		LogLevel:              getEnv("LOG_LEVEL", "info"),
+	}
+	if err := loadOrchestratorAuth(cfg); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// loadOrchestratorAuth reads the TLS and token settings of the orchestrator
+// connection. Certificates and tokens require TLS, so credentials never
+// cross the network in plaintext.
+func loadOrchestratorAuth(cfg *Config) error {
+	var err error
+	cfg.OrchestratorTLS, err = strconv.ParseBool(getEnv("ORCHESTRATOR_TLS", "false"))
+	if err != nil {
+		return fmt.Errorf("invalid ORCHESTRATOR_TLS: %w", err)
+	}
+	cfg.OrchestratorCAFile = getEnv("ORCHESTRATOR_CA_FILE", "")
+	cfg.OrchestratorServerName = getEnv("ORCHESTRATOR_SERVER_NAME", "")
+	cfg.OrchestratorCertFile = getEnv("ORCHESTRATOR_CERT_FILE", "")
+	cfg.OrchestratorKeyFile = getEnv("ORCHESTRATOR_KEY_FILE", "")
+	cfg.OrchestratorTokenFile = getEnv("ORCHESTRATOR_TOKEN_FILE", "")
+
+	if !cfg.OrchestratorTLS {
+		for _, setting := range [][2]string{
+			{"ORCHESTRATOR_CA_FILE", cfg.OrchestratorCAFile},
+			{"ORCHESTRATOR_SERVER_NAME", cfg.OrchestratorServerName},
+			{"ORCHESTRATOR_CERT_FILE", cfg.OrchestratorCertFile},
+			{"ORCHESTRATOR_KEY_FILE", cfg.OrchestratorKeyFile},
+			{"ORCHESTRATOR_TOKEN_FILE", cfg.OrchestratorTokenFile},
+		} {
+			if setting[1] != "" {
+				return fmt.Errorf("%s requires ORCHESTRATOR_TLS=true", setting[0])
+			}
+		}
+	}
+	if (cfg.OrchestratorCertFile == "") != (cfg.OrchestratorKeyFile == "") {
+		return fmt.Errorf("ORCHESTRATOR_CERT_FILE and ORCHESTRATOR_KEY_FILE must be set together")
+	}
+	return nil
+}
+
+// loadGitAuth reads Git credentials. A password file takes precedence over a
+// token file, a token passed in GIT_TOKEN (e.g. a GitHub App installation
+// token from the dispatcher) and credentials embedded in REPOSITORY_URL,
+// which are removed from the URL so they never reach logs or reports.
+func loadGitAuth(cfg *Config) error {
+	var urlUser, urlPassword string
+	if u, err := url.Parse(cfg.GitURL); err == nil && u.User != nil && (u.Scheme == "https" || u.Scheme == "http") {
+		if password, ok := u.User.Password(); ok {
+			urlUser, urlPassword = u.User.Username(), password
+		} else {
+			// https://<token>@host/...
+			urlPassword = u.User.Username()
+		}
+		u.User = nil
+		cfg.GitURL = u.String()
+	}
+
+	cfg.GitUsername = getEnv("GIT_USERNAME", urlUser)
+	switch {
+	case os.Getenv("GIT_PASSWORD_FILE") != "":
+		password, err := readSecretFile(os.Getenv("GIT_PASSWORD_FILE"))
+		if err != nil {
+			return fmt.Errorf("invalid GIT_PASSWORD_FILE: %w", err)
+		}
+		cfg.GitPassword = password
+	case os.Getenv("GIT_TOKEN_FILE") != "":
+		token, err := readSecretFile(os.Getenv("GIT_TOKEN_FILE"))
+		if err != nil {
+			return fmt.Errorf("invalid GIT_TOKEN_FILE: %w", err)
+		}
+		cfg.GitPassword = token
+	case os.Getenv("GIT_TOKEN") != "":
+		cfg.GitPassword = os.Getenv("GIT_TOKEN")
+	default:
+		cfg.GitPassword = urlPassword
+	}
+
+	cfg.GitSSHKeyFile = getEnv("GIT_SSH_KEY_FILE", "")
+	cfg.GitKnownHostsFile = getEnv("GIT_KNOWN_HOSTS_FILE", "")
+	if cfg.GitSSHKeyFile != "" && cfg.GitKnownHostsFile == "" {
+		return fmt.Errorf("GIT_SSH_KEY_FILE requires GIT_KNOWN_HOSTS_FILE")
+	}
+	return nil
+}
+
+// readSecretFile reads a mounted secret, dropping the trailing newline
+func readSecretFile(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	secret := strings.TrimRight(string(data), "\r\n")
+	if secret == "" {
+		return "", fmt.Errorf("%s is empty", path)
+	}
+	return secret, nil
+}
+
 func getEnv(key, defaultValue string) string {
 	if value := os.Getenv(key); value != "" {
 		return value
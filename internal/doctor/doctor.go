@@ -0,0 +1,254 @@
+package doctor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"syscall"
+
+	"github.com/cloud-scan/cloudscan-runner/internal/enrich"
+	"github.com/cloud-scan/cloudscan-runner/internal/overrides"
+	"github.com/cloud-scan/cloudscan-runner/internal/policy"
+	"github.com/cloud-scan/cloudscan-runner/internal/scanners"
+)
+
+// minGitVersion is the oldest git reading configuration from
+// GIT_CONFIG_COUNT, which passes clone credentials
+const minGitVersion = "2.31.0"
+
+// Status is the outcome of a check
+type Status string
+
+const (
+	// StatusOK means the check passed
+	StatusOK Status = "ok"
+	// StatusWarn means scans can run but may be slow or degraded
+	StatusWarn Status = "warn"
+	// StatusFail means scans will fail or miss findings
+	StatusFail Status = "fail"
+	// StatusSkip means the check does not apply to this environment
+	StatusSkip Status = "skip"
+)
+
+// Check is the result of a single check
+type Check struct {
+	Name   string `json:"name"`
+	Status Status `json:"status"`
+	Detail string `json:"detail,omitempty"`
+}
+
+// Options describes the environment to check
+type Options struct {
+	ManifestDir  string // Scanner manifests registered before the scanner checks
+	WorkDir      string // Must be writable with MinFreeBytes available
+	ResultsDir   string // Must be writable
+	MinFreeBytes uint64 // Free space required in WorkDir
+
+	// Optional data files, parsed as a scan would; empty paths are skipped
+	PolicyFile            string
+	SeverityOverridesFile string
+	EPSSDataFile          string
+	KEVDataFile           string
+
+	// Orchestrator is the endpoint reached by Connect; the check is skipped
+	// when it is empty
+	Orchestrator string
+	Connect      func(ctx context.Context) error
+}
+
+// Report is the result of all checks
+type Report struct {
+	Checks []Check `json:"checks"`
+}
+
+// Run checks the scanner toolchain and the runtime environment
+func Run(ctx context.Context, opts Options) *Report {
+	r := &Report{}
+
+	loaded, err := scanners.LoadManifests(opts.ManifestDir)
+	switch {
+	case err != nil:
+		r.add("scanner manifests", StatusFail, err.Error())
+	case loaded > 0:
+		r.add("scanner manifests", StatusOK, fmt.Sprintf("%d loaded from %s", loaded, opts.ManifestDir))
+	}
+	for _, reg := range scanners.Registrations() {
+		r.checkScanner(ctx, reg)
+	}
+
+	r.checkGit(ctx)
+	r.checkWritable("work dir", opts.WorkDir)
+	r.checkWritable("results dir", opts.ResultsDir)
+	r.checkFreeSpace(opts.WorkDir, opts.MinFreeBytes)
+
+	r.checkDataFiles(opts)
+
+	r.checkOrchestrator(ctx, opts)
+	return r
+}
+
+// Count returns the number of checks with a status
+func (r *Report) Count(status Status) int {
+	n := 0
+	for _, c := range r.Checks {
+		if c.Status == status {
+			n++
+		}
+	}
+	return n
+}
+
+// add appends a check result
+func (r *Report) add(name string, status Status, detail string) {
+	r.Checks = append(r.Checks, Check{Name: name, Status: status, Detail: detail})
+}
+
+// checkScanner checks a scanner's binary, version and data
+func (r *Report) checkScanner(ctx context.Context, reg scanners.Registration) {
+	name := "scanner " + reg.Name
+	scanner := reg.New()
+	if !scanner.IsAvailable() {
+		r.add(name, StatusFail, "not installed")
+		return
+	}
+
+	versioner, ok := scanner.(scanners.Versioner)
+	switch {
+	case !ok && reg.MinVersion != "":
+		r.add(name, StatusWarn, fmt.Sprintf("version unknown, requires %s or later", reg.MinVersion))
+	case !ok:
+		r.add(name, StatusOK, "installed")
+	default:
+		version, err := versioner.Version(ctx)
+		switch {
+		case err != nil && reg.MinVersion != "":
+			r.add(name, StatusFail, fmt.Sprintf("version unknown, requires %s or later: %v", reg.MinVersion, err))
+		case err != nil:
+			r.add(name, StatusWarn, err.Error())
+		case reg.MinVersion != "" && scanners.CompareVersions(version, reg.MinVersion) < 0:
+			r.add(name, StatusFail, fmt.Sprintf("version %s, requires %s or later", version, reg.MinVersion))
+		default:
+			r.add(name, StatusOK, "version "+version)
+		}
+	}
+
+	checker, ok := scanner.(scanners.DataChecker)
+	if !ok {
+		return
+	}
+	detail, err := checker.CheckData(ctx)
+	switch {
+	case errors.Is(err, scanners.ErrDataOnDemand):
+		r.add(name+" data", StatusWarn, fmt.Sprintf("%s; %v", detail, err))
+	case err != nil:
+		r.add(name+" data", StatusFail, err.Error())
+	case detail != "":
+		r.add(name+" data", StatusOK, detail)
+	}
+}
+
+// checkGit checks that a recent enough git is installed
+func (r *Report) checkGit(ctx context.Context) {
+	if _, err := exec.LookPath("git"); err != nil {
+		r.add("git", StatusFail, "not installed")
+		return
+	}
+	output, err := exec.CommandContext(ctx, "git", "--version").Output()
+	if err != nil {
+		r.add("git", StatusFail, fmt.Sprintf("failed to run git --version: %v", err))
+		return
+	}
+	version := scanners.ExtractVersion(string(output))
+	if version == "" {
+		r.add("git", StatusFail, "no version in git --version output")
+		return
+	}
+	if scanners.CompareVersions(version, minGitVersion) < 0 {
+		r.add("git", StatusFail, fmt.Sprintf("version %s, requires %s or later", version, minGitVersion))
+		return
+	}
+	r.add("git", StatusOK, "version "+version)
+}
+
+// checkWritable checks that files can be created in a directory, creating
+// it like the runner would
+func (r *Report) checkWritable(name, dir string) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		r.add(name, StatusFail, err.Error())
+		return
+	}
+	file, err := os.CreateTemp(dir, ".doctor-")
+	if err != nil {
+		r.add(name, StatusFail, fmt.Sprintf("%s is not writable: %v", dir, err))
+		return
+	}
+	file.Close()
+	os.Remove(file.Name())
+	r.add(name, StatusOK, dir)
+}
+
+// checkFreeSpace checks the space available in a directory
+func (r *Report) checkFreeSpace(dir string, minFree uint64) {
+	var stat syscall.Statfs_t
+	if err := syscall.Statfs(dir, &stat); err != nil {
+		r.add("disk space", StatusFail, err.Error())
+		return
+	}
+	free := stat.Bavail * uint64(stat.Bsize)
+	detail := fmt.Sprintf("%s free in %s", formatBytes(free), dir)
+	if free < minFree {
+		r.add("disk space", StatusFail, fmt.Sprintf("%s, requires %s", detail, formatBytes(minFree)))
+		return
+	}
+	r.add("disk space", StatusOK, detail)
+}
+
+// checkDataFiles loads each configured data file the way a scan does, so
+// a malformed file fails here rather than in the job
+func (r *Report) checkDataFiles(opts Options) {
+	if opts.PolicyFile != "" {
+		if pol, err := policy.Load(opts.PolicyFile); err != nil {
+			r.add("POLICY_FILE", StatusFail, err.Error())
+		} else {
+			r.add("POLICY_FILE", StatusOK, fmt.Sprintf("%d rules in %s", len(pol.Rules), opts.PolicyFile))
+		}
+	}
+	if opts.SeverityOverridesFile != "" {
+		if table, err := overrides.Load(opts.SeverityOverridesFile); err != nil {
+			r.add("SEVERITY_OVERRIDES_FILE", StatusFail, err.Error())
+		} else {
+			r.add("SEVERITY_OVERRIDES_FILE", StatusOK, fmt.Sprintf("%d overrides in %s", len(table.Overrides), opts.SeverityOverridesFile))
+		}
+	}
+
+	enricher := enrich.New()
+	if opts.EPSSDataFile != "" {
+		if err := enricher.LoadEPSS(opts.EPSSDataFile); err != nil {
+			r.add("EPSS_DATA_FILE", StatusFail, err.Error())
+		} else {
+			r.add("EPSS_DATA_FILE", StatusOK, opts.EPSSDataFile)
+		}
+	}
+	if opts.KEVDataFile != "" {
+		if err := enricher.LoadKEV(opts.KEVDataFile); err != nil {
+			r.add("KEV_DATA_FILE", StatusFail, err.Error())
+		} else {
+			r.add("KEV_DATA_FILE", StatusOK, opts.KEVDataFile)
+		}
+	}
+}
+
+// checkOrchestrator checks that the orchestrator accepts connections
+func (r *Report) checkOrchestrator(ctx context.Context, opts Options) {
+	if opts.Orchestrator == "" || opts.Connect == nil {
+		r.add("orchestrator", StatusSkip, "ORCHESTRATOR_ENDPOINT not set")
+		return
+	}
+	if err := opts.Connect(ctx); err != nil {
+		r.add("orchestrator", StatusFail, err.Error())
+		return
+	}
+	r.add("orchestrator", StatusOK, opts.Orchestrator)
+}
@@ -0,0 +1,435 @@
+package downloader
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/bzip2"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"mime"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+	log "github.com/sirupsen/logrus"
+	"github.com/ulikunitz/xz"
+)
+
+// archiveFormat identifies the format of a source archive
+type archiveFormat string
+
+const (
+	formatZip      archiveFormat = "zip"
+	formatTar      archiveFormat = "tar"
+	formatTarGzip  archiveFormat = "tar.gz"
+	formatTarBzip2 archiveFormat = "tar.bz2"
+	formatTarXz    archiveFormat = "tar.xz"
+	formatTarZstd  archiveFormat = "tar.zst"
+)
+
+// sniffSize is the number of leading bytes needed to detect a format; the
+// tar magic is at offset 257
+const sniffSize = 512
+
+var magics = []struct {
+	offset int
+	magic  []byte
+	format archiveFormat
+}{
+	{0, []byte("PK\x03\x04"), formatZip},
+	{0, []byte("PK\x05\x06"), formatZip}, // Empty archive
+	{0, []byte{0x1f, 0x8b}, formatTarGzip},
+	{0, []byte("BZh"), formatTarBzip2},
+	{0, []byte{0xfd, '7', 'z', 'X', 'Z', 0x00}, formatTarXz},
+	{0, []byte{0x28, 0xb5, 0x2f, 0xfd}, formatTarZstd},
+	{257, []byte("ustar"), formatTar},
+}
+
+var contentTypes = map[string]archiveFormat{
+	"application/zip":              formatZip,
+	"application/x-zip-compressed": formatZip,
+	"application/x-tar":            formatTar,
+	"application/gzip":             formatTarGzip,
+	"application/x-gzip":           formatTarGzip,
+	"application/x-gtar":           formatTarGzip,
+	"application/x-bzip2":          formatTarBzip2,
+	"application/x-xz":             formatTarXz,
+	"application/zstd":             formatTarZstd,
+	"application/x-zstd":           formatTarZstd,
+}
+
+// detectFormat identifies an archive by its leading bytes, falling back to
+// the Content-Type for formats without a reliable magic (old tar archives)
+func detectFormat(header []byte, contentType string) (archiveFormat, error) {
+	for _, m := range magics {
+		if len(header) >= m.offset+len(m.magic) && bytes.Equal(header[m.offset:m.offset+len(m.magic)], m.magic) {
+			return m.format, nil
+		}
+	}
+	if mediaType, _, err := mime.ParseMediaType(contentType); err == nil {
+		if format, ok := contentTypes[mediaType]; ok {
+			return format, nil
+		}
+	}
+	return "", fmt.Errorf("unsupported archive format (content type %q)", contentType)
+}
+
+// decompress wraps a compressed tar stream in its decompressor
+func decompress(format archiveFormat, r io.Reader) (io.ReadCloser, error) {
+	switch format {
+	case formatTar:
+		return io.NopCloser(r), nil
+	case formatTarGzip:
+		return gzip.NewReader(r)
+	case formatTarBzip2:
+		return io.NopCloser(bzip2.NewReader(r)), nil
+	case formatTarXz:
+		xr, err := xz.NewReader(r)
+		if err != nil {
+			return nil, err
+		}
+		return io.NopCloser(xr), nil
+	case formatTarZstd:
+		zr, err := zstd.NewReader(r)
+		if err != nil {
+			return nil, err
+		}
+		return zr.IOReadCloser(), nil
+	default:
+		return nil, fmt.Errorf("%s is not a tar format", format)
+	}
+}
+
+// extractTar extracts a tar stream to the destination directory.
+// compressed reports the archive bytes consumed, for the ratio limit.
+func (d *Downloader) extractTar(r io.Reader, destDir string, compressed func() int64) error {
+	e, err := d.newExtraction(destDir, compressed)
+	if err != nil {
+		return err
+	}
+
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return e.finish()
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read tar: %w", err)
+		}
+		if err := e.tarEntry(tr, hdr); err != nil {
+			return fmt.Errorf("failed to extract %s: %w", hdr.Name, err)
+		}
+	}
+}
+
+// tarEntry extracts a single tar entry
+func (e *extraction) tarEntry(tr *tar.Reader, hdr *tar.Header) error {
+	if hdr.Typeflag == tar.TypeXGlobalHeader {
+		return nil
+	}
+	if err := e.guard.entry(); err != nil {
+		return err
+	}
+
+	switch hdr.Typeflag {
+	case tar.TypeDir:
+		return e.dir(hdr.Name)
+	case tar.TypeReg:
+		return e.file(hdr.Name, tr, hdr.FileInfo().Mode())
+	case tar.TypeSymlink:
+		return e.symlink(hdr.Name, hdr.Linkname)
+	case tar.TypeLink:
+		return e.hardlink(hdr.Name, hdr.Linkname)
+	default:
+		// Devices and FIFOs are not source code
+		e.logger.WithFields(log.Fields{
+			"name": hdr.Name,
+			"type": string(hdr.Typeflag),
+		}).Debug("Skipping tar entry")
+		return nil
+	}
+}
+
+// extractZip extracts a zip archive to destination directory
+func (d *Downloader) extractZip(zipPath, destDir string) error {
+	r, err := zip.OpenReader(zipPath)
+	if err != nil {
+		return fmt.Errorf("failed to open zip: %w", err)
+	}
+	defer r.Close()
+
+	info, err := os.Stat(zipPath)
+	if err != nil {
+		return fmt.Errorf("failed to open zip: %w", err)
+	}
+
+	// Entries are decompressed from sections of their declared compressed
+	// size. The sizes are declared by the archive itself, and entries may
+	// overlap, so the total is clamped to the size of the file.
+	var compressed int64
+	e, err := d.newExtraction(destDir, func() int64 { return min(compressed, info.Size()) })
+	if err != nil {
+		return err
+	}
+
+	for _, f := range r.File {
+		compressed += int64(min(f.CompressedSize64, uint64(info.Size())))
+		err := e.zipEntry(f)
+		if err != nil {
+			return fmt.Errorf("failed to extract %s: %w", f.Name, err)
+		}
+	}
+
+	return e.finish()
+}
+
+// zipEntry extracts a single file from zip
+func (e *extraction) zipEntry(f *zip.File) error {
+	if err := e.guard.entry(); err != nil {
+		return err
+	}
+	if f.FileInfo().IsDir() {
+		return e.dir(f.Name)
+	}
+
+	// Open source file
+	srcFile, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer srcFile.Close()
+
+	if f.Mode()&os.ModeSymlink != 0 {
+		// The content of a zip symlink is its target
+		target, err := io.ReadAll(io.LimitReader(srcFile, maxLinkTarget))
+		if err != nil {
+			return err
+		}
+		return e.symlink(f.Name, string(target))
+	}
+	return e.file(f.Name, srcFile, f.Mode())
+}
+
+// maxLinkTarget bounds the length of a symlink target read from a zip
+const maxLinkTarget = 4096
+
+// extraction writes the entries of one archive into a directory, applying
+// the extraction limits and link policy
+type extraction struct {
+	root     string
+	guard    guard
+	links    LinkPolicy
+	symlinks []pendingLink // Created once all files are written
+	skipped  int           // Links dropped by the policy
+	logger   *log.Entry
+}
+
+// pendingLink is a symlink waiting to be created
+type pendingLink struct {
+	name   string
+	path   string
+	target string
+}
+
+// newExtraction prepares the extraction of an archive into destDir
+func (d *Downloader) newExtraction(destDir string, compressed func() int64) (*extraction, error) {
+	root, err := filepath.Abs(destDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve destination directory: %w", err)
+	}
+	return &extraction{
+		root:   root,
+		guard:  guard{limits: d.limits, compressed: compressed},
+		links:  d.limits.Links,
+		logger: d.logger,
+	}, nil
+}
+
+// dir creates a directory entry
+func (e *extraction) dir(name string) error {
+	path, err := safePath(e.root, name)
+	if err != nil {
+		return err
+	}
+	return os.MkdirAll(path, 0755)
+}
+
+// file writes a regular file entry. Setuid, setgid and sticky bits and
+// group or world write access are dropped; only the executable bit is kept.
+func (e *extraction) file(name string, r io.Reader, mode os.FileMode) error {
+	path, err := safePath(e.root, name)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+
+	// Replace rather than write through an earlier entry, e.g. a hardlink
+	if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	perm := os.FileMode(0644)
+	if mode&0111 != 0 {
+		perm = 0755
+	}
+	destFile, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, perm)
+	if err != nil {
+		return err
+	}
+
+	err = e.guard.copy(destFile, r)
+	if closeErr := destFile.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// symlink records a symlink entry to be created by finish
+func (e *extraction) symlink(name, target string) error {
+	path, err := safePath(e.root, name)
+	if err != nil {
+		return err
+	}
+	if e.links == LinksSkip {
+		e.skip(name, "links are skipped")
+		return nil
+	}
+	if filepath.IsAbs(target) || !within(e.root, filepath.Join(filepath.Dir(path), target)) {
+		e.skip(name, "symlink target is outside the destination")
+		return nil
+	}
+	e.symlinks = append(e.symlinks, pendingLink{name: name, path: path, target: target})
+	return nil
+}
+
+// hardlink links an entry to an earlier regular file of the archive
+func (e *extraction) hardlink(name, linkname string) error {
+	path, err := safePath(e.root, name)
+	if err != nil {
+		return err
+	}
+	if e.links == LinksSkip {
+		e.skip(name, "links are skipped")
+		return nil
+	}
+	source, err := safePath(e.root, linkname)
+	if err != nil {
+		e.skip(name, "hardlink target is outside the destination")
+		return nil
+	}
+	// No symlinks exist yet, so the source cannot lead outside
+	if info, err := os.Lstat(source); err != nil || !info.Mode().IsRegular() {
+		e.skip(name, "hardlink target is not an extracted file")
+		return nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return os.Link(source, path)
+}
+
+// finish creates the symlinks. They are created only after all files are
+// written, so nothing is ever written through one, and only links that
+// resolve to an existing path inside the destination are kept; this also
+// catches targets that escape through another link.
+func (e *extraction) finish() error {
+	var created []pendingLink
+	for _, link := range e.symlinks {
+		if linked, err := e.throughLink(filepath.Dir(link.path)); err != nil || linked {
+			e.skip(link.name, "symlink parent is a symlink")
+			continue
+		}
+		if err := os.MkdirAll(filepath.Dir(link.path), 0755); err != nil {
+			return fmt.Errorf("failed to extract %s: %w", link.name, err)
+		}
+		if err := os.RemoveAll(link.path); err != nil {
+			return fmt.Errorf("failed to extract %s: %w", link.name, err)
+		}
+		if err := os.Symlink(link.target, link.path); err != nil {
+			return fmt.Errorf("failed to extract %s: %w", link.name, err)
+		}
+		created = append(created, link)
+	}
+
+	root, err := filepath.EvalSymlinks(e.root)
+	if err != nil {
+		return fmt.Errorf("failed to resolve destination directory: %w", err)
+	}
+	for _, link := range created {
+		resolved, err := filepath.EvalSymlinks(link.path)
+		if err == nil && within(root, resolved) {
+			continue
+		}
+		if err := os.Remove(link.path); err != nil {
+			return fmt.Errorf("failed to remove %s: %w", link.name, err)
+		}
+		e.skip(link.name, "symlink does not resolve inside the destination")
+	}
+
+	if e.skipped > 0 {
+		e.logger.WithField("links", e.skipped).Info("Skipped archive links")
+	}
+	return nil
+}
+
+// throughLink reports whether a path inside the root passes through a symlink
+func (e *extraction) throughLink(path string) (bool, error) {
+	rel, err := filepath.Rel(e.root, path)
+	if err != nil {
+		return false, err
+	}
+	current := e.root
+	for _, part := range strings.Split(rel, string(os.PathSeparator)) {
+		if part == "." {
+			continue
+		}
+		current = filepath.Join(current, part)
+		info, err := os.Lstat(current)
+		if errors.Is(err, fs.ErrNotExist) {
+			return false, nil
+		}
+		if err != nil {
+			return false, err
+		}
+		if info.Mode()&os.ModeSymlink != 0 {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// skip records a link dropped by the link policy
+func (e *extraction) skip(name, reason string) {
+	e.skipped++
+	e.logger.WithFields(log.Fields{
+		"name":   name,
+		"reason": reason,
+	}).Debug("Skipping archive link")
+}
+
+// safePath joins an archive entry name to the destination directory,
+// rejecting names that escape it (ZipSlip)
+func safePath(destDir, name string) (string, error) {
+	root := filepath.Clean(destDir)
+	destPath := filepath.Join(root, name)
+	if !within(root, destPath) {
+		return "", fmt.Errorf("invalid file path: %s", name)
+	}
+	return destPath, nil
+}
+
+// within reports whether a clean path is root or inside it
+func within(root, path string) bool {
+	return path == root || strings.HasPrefix(path, root+string(os.PathSeparator))
+}
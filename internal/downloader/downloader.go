@@ -1,14 +1,17 @@
 package downloader
 
 import (
-	"archive/zip"
+	"bufio"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"os"
-	"os/exec"
 	"path/filepath"
+	"strings"
 	"time"
 
 	log "github.com/sirupsen/logrus"
@@ -17,6 +20,9 @@ import (
 // Downloader handles downloading source code from presigned URLs
 type Downloader struct {
 	httpClient *http.Client
+	gitCreds   *gitCredentials
+	limits     ExtractLimits
+	retries    int
 	logger     *log.Entry
 }
 
@@ -26,14 +32,22 @@ func New(timeout time.Duration) *Downloader {
 		httpClient: &http.Client{
 			Timeout: timeout,
 		},
-		logger: log.WithField("component", "downloader"),
+		limits:  DefaultExtractLimits,
+		retries: DefaultRetries,
+		logger:  log.WithField("component", "downloader"),
 	}
 }
 
-// DownloadAndExtract downloads a source archive from a presigned URL and extracts it
-func (d *Downloader) DownloadAndExtract(ctx context.Context, presignedURL, destDir string) error {
+// DownloadAndExtract downloads a source archive from a presigned URL, or
+// reads it from a file:// URL, and extracts it. The format is detected from the archive's leading bytes or
+// the Content-Type; tar archives are extracted while they download, zip
+// archives need random access and are saved to a temporary file first.
+// When expectedSHA256 is set, the archive is saved and verified before
+// anything is extracted.
+func (d *Downloader) DownloadAndExtract(ctx context.Context, presignedURL, destDir, expectedSHA256 string) error {
 	d.logger.WithFields(log.Fields{
 		"dest_dir": destDir,
+		"verify":   expectedSHA256 != "",
 	}).Info("Downloading source archive")
 
 	// Create destination directory
@@ -41,164 +55,134 @@ func (d *Downloader) DownloadAndExtract(ctx context.Context, presignedURL, destD
 		return fmt.Errorf("failed to create destination directory: %w", err)
 	}
 
-	// Download to temp file
-	tempFile := filepath.Join(os.TempDir(), "source.zip")
-	defer os.Remove(tempFile)
-
-	if err := d.downloadFile(ctx, presignedURL, tempFile); err != nil {
+	src, contentType, err := d.openSource(ctx, presignedURL)
+	if err != nil {
 		return fmt.Errorf("failed to download source: %w", err)
 	}
+	defer src.Close()
 
-	d.logger.Info("Download complete, extracting archive")
-
-	// Extract archive
-	if err := d.extractZip(tempFile, destDir); err != nil {
-		return fmt.Errorf("failed to extract source: %w", err)
+	if expectedSHA256 == "" {
+		return d.extract(src, contentType, destDir)
 	}
 
-	d.logger.Info("Source extracted successfully")
-	return nil
-}
-
-// downloadFile downloads a file from URL to local path
-func (d *Downloader) downloadFile(ctx context.Context, url, filepath string) error {
-	// Create request with context
-	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	tempFile, err := os.CreateTemp("", "source-*")
 	if err != nil {
-		return fmt.Errorf("failed to create request: %w", err)
+		return fmt.Errorf("failed to create temp file: %w", err)
 	}
+	defer os.Remove(tempFile.Name())
+	defer tempFile.Close()
 
-	// Execute request
-	resp, err := d.httpClient.Do(req)
-	if err != nil {
-		return fmt.Errorf("failed to download: %w", err)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("download failed with status: %s", resp.Status)
+	hash := sha256.New()
+	if _, err := io.Copy(io.MultiWriter(tempFile, hash), src); err != nil {
+		return fmt.Errorf("failed to download source: %w", err)
 	}
-
-	// Create output file
-	out, err := os.Create(filepath)
-	if err != nil {
-		return fmt.Errorf("failed to create file: %w", err)
+	if actual := hex.EncodeToString(hash.Sum(nil)); !strings.EqualFold(actual, expectedSHA256) {
+		return fmt.Errorf("checksum mismatch: expected SHA-256 %s, got %s", strings.ToLower(expectedSHA256), actual)
 	}
-	defer out.Close()
+	d.logger.Info("Checksum verified")
 
-	// Copy data
-	written, err := io.Copy(out, resp.Body)
-	if err != nil {
-		return fmt.Errorf("failed to write file: %w", err)
+	if _, err := tempFile.Seek(0, io.SeekStart); err != nil {
+		return fmt.Errorf("failed to read source: %w", err)
 	}
-
-	d.logger.WithField("bytes", written).Debug("File downloaded")
-	return nil
+	return d.extract(tempFile, contentType, destDir)
 }
 
-// extractZip extracts a zip archive to destination directory
-func (d *Downloader) extractZip(zipPath, destDir string) error {
-	r, err := zip.OpenReader(zipPath)
+// extract detects the format of an archive and extracts it
+func (d *Downloader) extract(src io.Reader, contentType, destDir string) error {
+	counted := &countingReader{r: src}
+	body := bufio.NewReaderSize(counted, sniffSize)
+	header, err := body.Peek(sniffSize)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("failed to download source: %w", err)
+	}
+	format, err := detectFormat(header, contentType)
 	if err != nil {
-		return fmt.Errorf("failed to open zip: %w", err)
+		return err
 	}
-	defer r.Close()
+	d.logger.WithField("format", format).Info("Extracting archive")
 
-	for _, f := range r.File {
-		err := d.extractFile(f, destDir)
+	switch {
+	case format == formatZip:
+		// A verified archive is already on disk
+		if file, ok := src.(*os.File); ok {
+			err = d.extractZip(file.Name(), destDir)
+		} else {
+			err = d.extractZipStream(body, destDir)
+		}
+	default:
+		var r io.ReadCloser
+		r, err = decompress(format, body)
 		if err != nil {
-			return fmt.Errorf("failed to extract %s: %w", f.Name, err)
+			return fmt.Errorf("failed to read %s archive: %w", format, err)
 		}
+		defer r.Close()
+		err = d.extractTar(r, destDir, func() int64 { return counted.n })
+	}
+	if err != nil {
+		return fmt.Errorf("failed to extract source: %w", err)
 	}
 
+	d.logger.Info("Source extracted successfully")
 	return nil
 }
 
-// extractFile extracts a single file from zip
-func (d *Downloader) extractFile(f *zip.File, destDir string) error {
-	// Construct destination path
-	destPath := filepath.Join(destDir, f.Name)
-
-	// Check for ZipSlip vulnerability
-	if !filepath.HasPrefix(destPath, filepath.Clean(destDir)+string(os.PathSeparator)) {
-		return fmt.Errorf("invalid file path: %s", f.Name)
-	}
-
-	if f.FileInfo().IsDir() {
-		return os.MkdirAll(destPath, f.Mode())
-	}
-
-	// Create parent directory
-	if err := os.MkdirAll(filepath.Dir(destPath), 0755); err != nil {
-		return err
-	}
-
-	// Open source file
-	srcFile, err := f.Open()
+// extractZipStream saves a zip archive to a temporary file and extracts it
+func (d *Downloader) extractZipStream(r io.Reader, destDir string) error {
+	tempFile, err := os.CreateTemp("", "source-*.zip")
 	if err != nil {
-		return err
+		return fmt.Errorf("failed to create temp file: %w", err)
 	}
-	defer srcFile.Close()
+	defer os.Remove(tempFile.Name())
 
-	// Create destination file
-	destFile, err := os.OpenFile(destPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, f.Mode())
+	written, err := io.Copy(tempFile, r)
+	if closeErr := tempFile.Close(); err == nil {
+		err = closeErr
+	}
 	if err != nil {
-		return err
+		return fmt.Errorf("failed to write file: %w", err)
 	}
-	defer destFile.Close()
+	d.logger.WithField("bytes", written).Debug("File downloaded")
 
-	// Copy data
-	_, err = io.Copy(destFile, srcFile)
-	return err
+	return d.extractZip(tempFile.Name(), destDir)
 }
 
-// CloneGit clones a Git repository to the destination directory
-func (d *Downloader) CloneGit(ctx context.Context, repoURL, branch, commit, destDir string) error {
-	d.logger.WithFields(log.Fields{
-		"repo_url": repoURL,
-		"branch":   branch,
-		"commit":   commit,
-		"dest_dir": destDir,
-	}).Info("Cloning Git repository")
+// DownloadFile downloads a single file from a presigned or file:// URL to
+// destPath
+func (d *Downloader) DownloadFile(ctx context.Context, presignedURL, destPath string) error {
+	d.logger.WithField("dest_path", destPath).Info("Downloading file")
 
-	// Create destination directory
-	if err := os.MkdirAll(destDir, 0755); err != nil {
+	if err := os.MkdirAll(filepath.Dir(destPath), 0755); err != nil {
 		return fmt.Errorf("failed to create destination directory: %w", err)
 	}
 
-	// Build git clone command
-	args := []string{"clone", "--depth=1"}
-
-	if branch != "" {
-		args = append(args, "--branch", branch)
+	if err := d.downloadFile(ctx, presignedURL, destPath); err != nil {
+		return fmt.Errorf("failed to download file: %w", err)
 	}
 
-	args = append(args, repoURL, destDir)
-
-	// Execute git clone
-	cmd := exec.CommandContext(ctx, "git", args...)
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-
-	d.logger.WithField("command", cmd.String()).Debug("Executing git clone")
+	return nil
+}
 
-	if err := cmd.Run(); err != nil {
-		return fmt.Errorf("failed to clone repository: %w", err)
+// downloadFile downloads a file from URL to local path
+func (d *Downloader) downloadFile(ctx context.Context, url, filepath string) error {
+	src, _, err := d.openSource(ctx, url)
+	if err != nil {
+		return err
 	}
+	defer src.Close()
 
-	// If specific commit is requested, checkout that commit
-	if commit != "" {
-		d.logger.WithField("commit", commit).Info("Checking out specific commit")
-
-		checkoutCmd := exec.CommandContext(ctx, "git", "-C", destDir, "checkout", commit)
-		checkoutCmd.Stdout = os.Stdout
-		checkoutCmd.Stderr = os.Stderr
+	// Create output file
+	out, err := os.Create(filepath)
+	if err != nil {
+		return fmt.Errorf("failed to create file: %w", err)
+	}
+	defer out.Close()
 
-		if err := checkoutCmd.Run(); err != nil {
-			return fmt.Errorf("failed to checkout commit %s: %w", commit, err)
-		}
+	// Copy data
+	written, err := io.Copy(out, src)
+	if err != nil {
+		return fmt.Errorf("failed to write file: %w", err)
 	}
 
-	d.logger.Info("Git clone completed successfully")
+	d.logger.WithField("bytes", written).Debug("File downloaded")
 	return nil
-}
\ No newline at end of file
+}
@@ -0,0 +1,193 @@
+package downloader
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// CloneOptions tunes a Git clone
+type CloneOptions struct {
+	// Depth is the number of commits of history to fetch; 0 fetches all
+	Depth int
+
+	// ShallowSince, when set, fetches only commits made since that time
+	// (Depth is then ignored)
+	ShallowSince time.Time
+
+	// Submodules initializes submodules recursively
+	Submodules bool
+
+	// LFS fetches Git LFS objects; otherwise LFS files stay pointer files
+	LFS bool
+}
+
+// CloneGit fetches a revision of a Git repository into the destination
+// directory and returns the SHA of the checked-out HEAD. An exact commit is
+// fetched directly, so it does not need to be a branch tip. ref may be a
+// branch or tag name, or a full ref such as refs/pull/123/head or
+// refs/pull/123/merge; when commit is set, ref is only used as a fallback
+// for servers that refuse to serve commits by SHA.
+func (d *Downloader) CloneGit(ctx context.Context, repoURL, ref, commit, destDir string, opts CloneOptions) (string, error) {
+	d.logger.WithFields(log.Fields{
+		"repo_url":   RedactURL(repoURL),
+		"ref":        ref,
+		"commit":     commit,
+		"dest_dir":   destDir,
+		"depth":      opts.Depth,
+		"since":      opts.ShallowSince,
+		"submodules": opts.Submodules,
+		"lfs":        opts.LFS,
+	}).Info("Cloning Git repository")
+
+	// Create destination directory
+	if err := os.MkdirAll(destDir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create destination directory: %w", err)
+	}
+
+	if err := d.git(ctx, destDir, "init", "--quiet"); err != nil {
+		return "", fmt.Errorf("failed to initialize repository: %w", err)
+	}
+	if err := d.git(ctx, destDir, "remote", "add", "origin", repoURL); err != nil {
+		return "", fmt.Errorf("failed to add remote: %w", err)
+	}
+	if d.gitCreds != nil {
+		// Later fetches in the clone, e.g. of a base branch, authenticate too
+		for _, kv := range d.gitCreds.config {
+			if err := d.git(ctx, destDir, "config", "--add", kv[0], kv[1]); err != nil {
+				return "", fmt.Errorf("failed to configure credentials: %w", err)
+			}
+		}
+	}
+
+	ref = normalizeRef(ref)
+	target := ref
+	if commit != "" {
+		target = commit
+	}
+
+	if err := d.fetch(ctx, destDir, target, opts); err != nil {
+		if commit == "" {
+			return "", fmt.Errorf("failed to fetch %s: %w", target, err)
+		}
+
+		// Some servers only serve advertised refs; fetch the ref's full
+		// history instead and find the commit in it
+		d.logger.WithError(err).WithField("ref", ref).Warn("Fetching commit by SHA failed, fetching ref history")
+		if err := d.fetch(ctx, destDir, ref, CloneOptions{}); err != nil {
+			return "", fmt.Errorf("failed to fetch %s: %w", ref, err)
+		}
+		target = commit
+	} else {
+		target = "FETCH_HEAD"
+	}
+
+	if err := d.git(ctx, destDir, "checkout", "--quiet", "--detach", target); err != nil {
+		return "", fmt.Errorf("failed to checkout %s: %w", target, err)
+	}
+
+	head, err := d.gitOutput(ctx, destDir, "rev-parse", "HEAD")
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve HEAD: %w", err)
+	}
+	if commit != "" && !strings.HasPrefix(head, strings.ToLower(commit)) {
+		return "", fmt.Errorf("checked out %s instead of requested commit %s", head, commit)
+	}
+
+	if opts.Submodules {
+		d.logger.Info("Initializing submodules")
+		if err := d.git(ctx, destDir, "submodule", "update", "--init", "--recursive", "--jobs=4"); err != nil {
+			return "", fmt.Errorf("failed to initialize submodules: %w", err)
+		}
+	}
+
+	if opts.LFS {
+		d.logger.Info("Fetching Git LFS objects")
+		if err := d.git(ctx, destDir, "lfs", "pull"); err != nil {
+			return "", fmt.Errorf("failed to fetch LFS objects: %w", err)
+		}
+		if opts.Submodules {
+			if err := d.git(ctx, destDir, "submodule", "foreach", "--recursive", "git lfs pull"); err != nil {
+				return "", fmt.Errorf("failed to fetch submodule LFS objects: %w", err)
+			}
+		}
+	}
+
+	d.logger.WithField("head", head).Info("Git clone completed successfully")
+	return head, nil
+}
+
+// fetch fetches a ref or commit from origin with the requested history
+func (d *Downloader) fetch(ctx context.Context, dir, target string, opts CloneOptions) error {
+	args := []string{"fetch", "--no-tags", "--quiet"}
+	switch {
+	case !opts.ShallowSince.IsZero():
+		args = append(args, "--shallow-since="+opts.ShallowSince.UTC().Format(time.RFC3339))
+	case opts.Depth > 0:
+		args = append(args, "--depth="+strconv.Itoa(opts.Depth))
+	}
+	args = append(args, "origin", target)
+	return d.git(ctx, dir, args...)
+}
+
+// normalizeRef expands shorthand pull request refs; other names are left
+// for git to resolve against branches and tags
+func normalizeRef(ref string) string {
+	switch {
+	case ref == "":
+		return "HEAD"
+	case strings.HasPrefix(ref, "pull/") || strings.HasPrefix(ref, "merge-requests/"):
+		return "refs/" + ref
+	default:
+		return ref
+	}
+}
+
+// git runs a git command in dir. LFS smudging is disabled so checkouts do
+// not download LFS objects unless they are pulled explicitly.
+func (d *Downloader) git(ctx context.Context, dir string, args ...string) error {
+	cmd := d.gitCommand(ctx, dir, args...)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+
+	d.logger.WithField("command", redactCommand(cmd.Args)).Debug("Executing git")
+	return cmd.Run()
+}
+
+// gitOutput runs a git command in dir and returns its trimmed output
+func (d *Downloader) gitOutput(ctx context.Context, dir string, args ...string) (string, error) {
+	cmd := d.gitCommand(ctx, dir, args...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("%w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// gitCommand builds a git command running in dir
+func (d *Downloader) gitCommand(ctx context.Context, dir string, args ...string) *exec.Cmd {
+	cmd := exec.CommandContext(ctx, "git", append([]string{"-C", dir}, args...)...)
+	cmd.Env = append(os.Environ(), "GIT_LFS_SKIP_SMUDGE=1", "GIT_TERMINAL_PROMPT=0")
+	if d.gitCreds != nil {
+		cmd.Env = append(cmd.Env, d.gitCreds.env()...)
+	}
+	return cmd
+}
+
+// redactCommand formats a command line with URL credentials hidden
+func redactCommand(args []string) string {
+	redacted := make([]string, len(args))
+	for i, arg := range args {
+		redacted[i] = RedactURL(arg)
+	}
+	return strings.Join(redacted, " ")
+}
@@ -0,0 +1,131 @@
+package downloader
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// GitAuth holds the credentials used to fetch from a Git remote
+type GitAuth struct {
+	// Username and Password authenticate HTTPS remotes; Password may be a
+	// personal access token or a GitHub App installation token
+	Username string
+	Password string
+
+	// SSHKeyFile is a private key for SSH remotes; KnownHostsFile is
+	// required with it so host keys are always verified
+	SSHKeyFile     string
+	KnownHostsFile string
+}
+
+// gitCredentials makes credentials available to git without putting them
+// on a command line: HTTPS credentials are served by a credential helper
+// reading a private file, SSH uses a private copy of the key
+type gitCredentials struct {
+	dir    string
+	config [][2]string // Git configuration applied to every command
+}
+
+// SetGitAuth configures the credentials used by CloneGit. The files they
+// are written to are removed by Close.
+func (d *Downloader) SetGitAuth(auth GitAuth) error {
+	if auth.Password == "" && auth.SSHKeyFile == "" {
+		return nil
+	}
+	if auth.SSHKeyFile != "" && auth.KnownHostsFile == "" {
+		return fmt.Errorf("an SSH key requires a known_hosts file")
+	}
+
+	dir, err := os.MkdirTemp("", "cloudscan-git-")
+	if err != nil {
+		return fmt.Errorf("failed to create credentials directory: %w", err)
+	}
+	creds := &gitCredentials{dir: dir}
+
+	if auth.Password != "" {
+		username := auth.Username
+		if username == "" {
+			// Accepted by GitHub for installation and personal access tokens
+			username = "x-access-token"
+		}
+		path := filepath.Join(dir, "credentials")
+		content := fmt.Sprintf("username=%s\npassword=%s\n", username, auth.Password)
+		if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+			os.RemoveAll(dir)
+			return fmt.Errorf("failed to write git credentials: %w", err)
+		}
+		// An empty value first resets helpers configured elsewhere
+		creds.config = append(creds.config,
+			[2]string{"credential.helper", ""},
+			[2]string{"credential.helper", fmt.Sprintf("!f() { test \"$1\" = get && cat %s; }; f", shellQuote(path))},
+		)
+	}
+
+	if auth.SSHKeyFile != "" {
+		key, err := os.ReadFile(auth.SSHKeyFile)
+		if err != nil {
+			os.RemoveAll(dir)
+			return fmt.Errorf("failed to read SSH key: %w", err)
+		}
+		// ssh rejects keys readable by others, as mounted secrets often are
+		keyPath := filepath.Join(dir, "id")
+		if err := os.WriteFile(keyPath, key, 0600); err != nil {
+			os.RemoveAll(dir)
+			return fmt.Errorf("failed to write SSH key: %w", err)
+		}
+		creds.config = append(creds.config, [2]string{"core.sshCommand", fmt.Sprintf(
+			"ssh -i %s -o IdentitiesOnly=yes -o UserKnownHostsFile=%s -o StrictHostKeyChecking=yes -o BatchMode=yes",
+			shellQuote(keyPath), shellQuote(auth.KnownHostsFile))})
+	}
+
+	d.Close()
+	d.gitCreds = creds
+	return nil
+}
+
+// Close removes the credential files written by SetGitAuth
+func (d *Downloader) Close() error {
+	if d.gitCreds == nil {
+		return nil
+	}
+	err := os.RemoveAll(d.gitCreds.dir)
+	d.gitCreds = nil
+	return err
+}
+
+// env passes the credential configuration to git through the environment,
+// so it also applies to submodule and LFS commands git spawns
+func (c *gitCredentials) env() []string {
+	env := []string{"GIT_CONFIG_COUNT=" + strconv.Itoa(len(c.config))}
+	for i, kv := range c.config {
+		env = append(env,
+			fmt.Sprintf("GIT_CONFIG_KEY_%d=%s", i, kv[0]),
+			fmt.Sprintf("GIT_CONFIG_VALUE_%d=%s", i, kv[1]),
+		)
+	}
+	return env
+}
+
+// shellQuote quotes a value for a POSIX shell
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// RedactURL hides the password or token embedded in a URL
+func RedactURL(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil || u.User == nil {
+		return raw
+	}
+	if _, ok := u.User.Password(); ok {
+		u.User = url.UserPassword(u.User.Username(), "REDACTED")
+	} else if u.User.Username() != "" && u.Scheme != "ssh" {
+		// A lone username in an HTTPS URL is usually a token
+		u.User = url.User("REDACTED")
+	}
+	return u.String()
+}
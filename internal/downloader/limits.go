@@ -0,0 +1,125 @@
+package downloader
+
+import (
+	"errors"
+	"fmt"
+	"io"
+)
+
+// LinkPolicy decides what happens to symlinks and hardlinks in archives
+type LinkPolicy string
+
+const (
+	// LinksSkip drops all links
+	LinksSkip LinkPolicy = "skip"
+	// LinksWithin keeps links whose target stays inside the destination
+	LinksWithin LinkPolicy = "within"
+)
+
+// ExtractLimits bounds what an archive may expand to; zero disables a limit
+type ExtractLimits struct {
+	MaxTotalBytes int64   // Uncompressed bytes of all files
+	MaxFiles      int     // Entries of any kind
+	MaxFileBytes  int64   // Uncompressed bytes of a single file
+	MaxRatio      float64 // Uncompressed bytes per archive byte
+	Links         LinkPolicy
+}
+
+// DefaultExtractLimits are generous for source code but stop archive bombs
+var DefaultExtractLimits = ExtractLimits{
+	MaxTotalBytes: 10 << 30,
+	MaxFiles:      1_000_000,
+	MaxFileBytes:  1 << 30,
+	MaxRatio:      200,
+	Links:         LinksSkip,
+}
+
+// ratioFloor is the output size below which the ratio is not checked, so
+// small, highly compressible archives are not rejected
+const ratioFloor = 1 << 20
+
+// ErrLimitExceeded reports an archive exceeding an extraction limit
+var ErrLimitExceeded = errors.New("archive exceeds extraction limit")
+
+// SetExtractLimits configures the limits applied by DownloadAndExtract
+func (d *Downloader) SetExtractLimits(limits ExtractLimits) error {
+	switch limits.Links {
+	case "":
+		limits.Links = LinksSkip
+	case LinksSkip, LinksWithin:
+	default:
+		return fmt.Errorf("unknown link policy %q", limits.Links)
+	}
+	d.limits = limits
+	return nil
+}
+
+// guard tracks the size of an extraction against its limits
+type guard struct {
+	limits     ExtractLimits
+	compressed func() int64 // Archive bytes consumed so far
+	files      int
+	total      int64
+}
+
+// entry counts an archive entry
+func (g *guard) entry() error {
+	g.files++
+	if g.limits.MaxFiles > 0 && g.files > g.limits.MaxFiles {
+		return fmt.Errorf("%w: more than %d entries", ErrLimitExceeded, g.limits.MaxFiles)
+	}
+	return nil
+}
+
+// copy copies a file's content, checking the limits as it grows
+func (g *guard) copy(dst io.Writer, src io.Reader) error {
+	buf := make([]byte, 32*1024)
+	var size int64
+	for {
+		n, readErr := src.Read(buf)
+		if n > 0 {
+			size += int64(n)
+			g.total += int64(n)
+			if err := g.check(size); err != nil {
+				return err
+			}
+			if _, err := dst.Write(buf[:n]); err != nil {
+				return err
+			}
+		}
+		if errors.Is(readErr, io.EOF) {
+			return nil
+		}
+		if readErr != nil {
+			return readErr
+		}
+	}
+}
+
+// check verifies the limits after a file has grown to size bytes
+func (g *guard) check(size int64) error {
+	switch {
+	case g.limits.MaxFileBytes > 0 && size > g.limits.MaxFileBytes:
+		return fmt.Errorf("%w: file larger than %d bytes", ErrLimitExceeded, g.limits.MaxFileBytes)
+	case g.limits.MaxTotalBytes > 0 && g.total > g.limits.MaxTotalBytes:
+		return fmt.Errorf("%w: more than %d bytes in total", ErrLimitExceeded, g.limits.MaxTotalBytes)
+	}
+	if g.limits.MaxRatio > 0 && g.total > ratioFloor && g.compressed != nil {
+		if compressed := g.compressed(); compressed > 0 && float64(g.total)/float64(compressed) > g.limits.MaxRatio {
+			return fmt.Errorf("%w: compression ratio above %g", ErrLimitExceeded, g.limits.MaxRatio)
+		}
+	}
+	return nil
+}
+
+// countingReader counts the bytes read through it
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
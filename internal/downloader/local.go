@@ -0,0 +1,122 @@
+package downloader
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// CopyDir copies a source directory into destDir. The copy is made under
+// the same limits, link policy and mode sanitizing as archive extraction;
+// the source directory is only read.
+func (d *Downloader) CopyDir(ctx context.Context, srcDir, destDir string) error {
+	d.logger.WithFields(log.Fields{
+		"source_dir": srcDir,
+		"dest_dir":   destDir,
+	}).Info("Copying source directory")
+
+	if err := os.MkdirAll(destDir, 0755); err != nil {
+		return fmt.Errorf("failed to create destination directory: %w", err)
+	}
+	e, err := d.newExtraction(destDir, nil)
+	if err != nil {
+		return err
+	}
+
+	err = filepath.WalkDir(srcDir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(srcDir, path)
+		if err != nil || rel == "." {
+			return err
+		}
+		if err := e.guard.entry(); err != nil {
+			return err
+		}
+
+		switch mode := entry.Type(); {
+		case mode.IsDir():
+			return e.dir(rel)
+		case mode.IsRegular():
+			return copyFile(e, rel, path)
+		case mode&fs.ModeSymlink != 0:
+			target, err := os.Readlink(path)
+			if err != nil {
+				return err
+			}
+			return e.symlink(rel, target)
+		default:
+			d.logger.WithField("name", rel).Debug("Skipping special file")
+			return nil
+		}
+	})
+	if err != nil {
+		return fmt.Errorf("failed to copy source: %w", err)
+	}
+	if err := e.finish(); err != nil {
+		return fmt.Errorf("failed to copy source: %w", err)
+	}
+
+	d.logger.Info("Source copied successfully")
+	return nil
+}
+
+// copyFile copies a regular file into the extraction
+func copyFile(e *extraction, rel, path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
+	return e.file(rel, file, info.Mode())
+}
+
+// openLocal opens the file named by a file:// URL
+func openLocal(rawURL string) (*os.File, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid URL: %w", err)
+	}
+	if u.Host != "" && u.Host != "localhost" {
+		return nil, fmt.Errorf("file URL with remote host %q is not supported", u.Host)
+	}
+	return os.Open(u.Path)
+}
+
+// isFileURL reports whether a URL names a local file
+func isFileURL(rawURL string) bool {
+	return strings.HasPrefix(strings.ToLower(rawURL), "file://")
+}
+
+// openSource opens a download, reading file:// URLs from the local disk
+func (d *Downloader) openSource(ctx context.Context, rawURL string) (io.ReadCloser, string, error) {
+	if isFileURL(rawURL) {
+		file, err := openLocal(rawURL)
+		if err != nil {
+			return nil, "", err
+		}
+		return file, "", nil
+	}
+
+	t, err := d.open(ctx, rawURL)
+	if err != nil {
+		return nil, "", err
+	}
+	return t, t.header.Get("Content-Type"), nil
+}
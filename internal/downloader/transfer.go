@@ -0,0 +1,281 @@
+package downloader
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// DefaultRetries is how often a failed request is retried in a row
+	DefaultRetries = 5
+
+	retryBaseDelay   = time.Second
+	retryMaxDelay    = 30 * time.Second
+	progressInterval = 10 * time.Second
+)
+
+// errSourceChanged reports a download whose object changed while resuming
+var errSourceChanged = errors.New("source changed during download")
+
+// statusError reports an unsuccessful HTTP response
+type statusError struct {
+	status string
+	code   int
+}
+
+func (e *statusError) Error() string {
+	return "download failed with status: " + e.status
+}
+
+// networkError reports a failed connection or an interrupted body
+type networkError struct {
+	err error
+}
+
+func (e *networkError) Error() string {
+	return e.err.Error()
+}
+
+func (e *networkError) Unwrap() error {
+	return e.err
+}
+
+// SetRetries configures how often a failed request is retried in a row
+func (d *Downloader) SetRetries(retries int) {
+	d.retries = max(retries, 0)
+}
+
+// transfer is the body of a download. Failed requests are retried with
+// exponential backoff and jitter, and interrupted transfers resume where
+// they stopped with HTTP range requests.
+type transfer struct {
+	d      *Downloader
+	ctx    context.Context
+	url    string
+	body   io.ReadCloser
+	header http.Header // Headers of the first response
+	size   int64       // Total size, or -1 if unknown
+	etag   string      // Strong ETag pinning resumed requests to the object
+	offset int64       // Bytes delivered so far
+
+	failures   int // Consecutive failed attempts
+	started    time.Time
+	lastReport time.Time
+}
+
+// open starts a download, retrying until the first response arrives
+func (d *Downloader) open(ctx context.Context, url string) (*transfer, error) {
+	t := &transfer{
+		d:       d,
+		ctx:     ctx,
+		url:     url,
+		size:    -1,
+		started: time.Now(),
+	}
+	t.lastReport = t.started
+	for {
+		err := t.request()
+		if err == nil {
+			return t, nil
+		}
+		if err := t.retry(err); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// Read reads the body, transparently resuming after failures
+func (t *transfer) Read(p []byte) (int, error) {
+	for {
+		if t.body == nil {
+			if err := t.request(); err != nil {
+				if err := t.retry(err); err != nil {
+					return 0, err
+				}
+				continue
+			}
+		}
+
+		n, err := t.body.Read(p)
+		t.offset += int64(n)
+		if n > 0 {
+			t.failures = 0
+			t.progress()
+		}
+		if err == nil {
+			return n, nil
+		}
+		if errors.Is(err, io.EOF) && (t.size < 0 || t.offset >= t.size) {
+			t.d.logger.WithFields(log.Fields{
+				"bytes":    t.offset,
+				"duration": time.Since(t.started).Round(time.Millisecond),
+			}).Info("Download complete")
+			return n, io.EOF
+		}
+
+		// A body ending before its Content-Length was cut off
+		if errors.Is(err, io.EOF) {
+			err = io.ErrUnexpectedEOF
+		}
+		err = &networkError{err: err}
+		t.body.Close()
+		t.body = nil
+		if err := t.retry(err); err != nil {
+			return n, err
+		}
+		if n > 0 {
+			return n, nil
+		}
+	}
+}
+
+// Close closes the current response body
+func (t *transfer) Close() error {
+	if t.body == nil {
+		return nil
+	}
+	err := t.body.Close()
+	t.body = nil
+	return err
+}
+
+// request requests the bytes not delivered yet
+func (t *transfer) request() error {
+	req, err := http.NewRequestWithContext(t.ctx, "GET", t.url, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	if t.offset > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", t.offset))
+		if t.etag != "" {
+			req.Header.Set("If-Range", t.etag)
+		}
+	}
+
+	resp, err := t.d.httpClient.Do(req)
+	if err != nil {
+		err = fmt.Errorf("failed to download: %w", err)
+		if isNetworkError(err) {
+			return &networkError{err: err}
+		}
+		return err
+	}
+
+	switch {
+	case resp.StatusCode == http.StatusPartialContent && t.offset > 0:
+		if !strings.HasPrefix(resp.Header.Get("Content-Range"), fmt.Sprintf("bytes %d-", t.offset)) {
+			resp.Body.Close()
+			return fmt.Errorf("unexpected Content-Range %q", resp.Header.Get("Content-Range"))
+		}
+	case resp.StatusCode == http.StatusOK && t.offset == 0:
+		t.header = resp.Header
+		t.size = resp.ContentLength
+		if etag := resp.Header.Get("ETag"); !strings.HasPrefix(etag, "W/") {
+			t.etag = etag
+		}
+	case resp.StatusCode == http.StatusOK:
+		// The range was ignored: skip what was already delivered, unless
+		// the object itself changed
+		if t.etag != "" && resp.Header.Get("ETag") != t.etag {
+			resp.Body.Close()
+			return errSourceChanged
+		}
+		if _, err := io.CopyN(io.Discard, resp.Body, t.offset); err != nil {
+			resp.Body.Close()
+			return &networkError{err: fmt.Errorf("failed to skip to offset %d: %w", t.offset, err)}
+		}
+	default:
+		resp.Body.Close()
+		return &statusError{status: resp.Status, code: resp.StatusCode}
+	}
+
+	t.body = resp.Body
+	return nil
+}
+
+// retry waits before the next attempt after err, or returns an error if
+// the failure is permanent or the retries are exhausted
+func (t *transfer) retry(err error) error {
+	if !t.retryable(err) {
+		return err
+	}
+	t.failures++
+	if t.failures > t.d.retries {
+		return fmt.Errorf("giving up after %d retries: %w", t.d.retries, err)
+	}
+
+	// Full jitter spreads out runners retrying against the same backend
+	delay := time.Duration(rand.Int64N(int64(min(retryMaxDelay, retryBaseDelay<<(t.failures-1))) + 1))
+	t.d.logger.WithError(err).WithFields(log.Fields{
+		"attempt": t.failures,
+		"offset":  t.offset,
+		"delay":   delay.Round(time.Millisecond),
+	}).Warn("Download failed, retrying")
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-t.ctx.Done():
+		return t.ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// retryable reports whether a failure is transient: connection errors,
+// interrupted bodies, timeouts, throttling and server errors. Invalid URLs,
+// other client errors and responses that do not match the object being
+// resumed fail at once.
+func (t *transfer) retryable(err error) bool {
+	if t.ctx.Err() != nil {
+		return false
+	}
+	var status *statusError
+	if errors.As(err, &status) {
+		return status.code >= 500 || status.code == http.StatusRequestTimeout || status.code == http.StatusTooManyRequests
+	}
+	var netErr *networkError
+	return errors.As(err, &netErr)
+}
+
+// isNetworkError reports whether a failed request failed in the network
+// rather than, say, on an unsupported URL or an invalid certificate
+func isNetworkError(err error) bool {
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		// url.Error is itself a net.Error; look at its cause
+		err = urlErr.Err
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) || errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, syscall.ECONNRESET)
+}
+
+// progress periodically logs the bytes transferred and the rate
+func (t *transfer) progress() {
+	now := time.Now()
+	if now.Sub(t.lastReport) < progressInterval {
+		return
+	}
+	t.lastReport = now
+
+	fields := log.Fields{
+		"bytes":          t.offset,
+		"bytes_per_sec":  int64(float64(t.offset) / now.Sub(t.started).Seconds()),
+		"content_length": t.size,
+	}
+	if t.size > 0 {
+		fields["percent"] = t.offset * 100 / t.size
+	}
+	t.d.logger.WithFields(fields).Info("Download progress")
+}
@@ -0,0 +1,151 @@
+package enrich
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// EPSSScore is the EPSS exploitation probability of a CVE
+type EPSSScore struct {
+	Probability float64
+	Percentile  float64
+}
+
+// KEVEntry is a CISA Known Exploited Vulnerabilities catalog entry
+type KEVEntry struct {
+	CVEID                      string `json:"cveID"`
+	VulnerabilityName          string `json:"vulnerabilityName"`
+	DateAdded                  string `json:"dateAdded"`
+	DueDate                    string `json:"dueDate"`
+	KnownRansomwareCampaignUse string `json:"knownRansomwareCampaignUse"`
+}
+
+// String describes the entry in finding details
+func (k KEVEntry) String() string {
+	s := "listed " + k.DateAdded
+	if k.DueDate != "" {
+		s += ", remediation due " + k.DueDate
+	}
+	if strings.EqualFold(k.KnownRansomwareCampaignUse, "Known") {
+		s += ", used in ransomware campaigns"
+	}
+	return s
+}
+
+// LoadEPSS reads the EPSS daily CSV published by FIRST (optionally gzipped):
+// a "#model_version..." comment line, a "cve,epss,percentile" header and one
+// row per CVE
+func (e *Enricher) LoadEPSS(path string) error {
+	r, closer, err := openData(path)
+	if err != nil {
+		return fmt.Errorf("failed to open EPSS data: %w", err)
+	}
+	defer closer()
+
+	reader := csv.NewReader(r)
+	reader.Comment = '#'
+	reader.FieldsPerRecord = -1
+
+	header, err := reader.Read()
+	if err != nil {
+		return fmt.Errorf("failed to read EPSS header: %w", err)
+	}
+	cveCol, epssCol, pctCol := -1, -1, -1
+	for i, name := range header {
+		switch strings.ToLower(strings.TrimSpace(name)) {
+		case "cve":
+			cveCol = i
+		case "epss":
+			epssCol = i
+		case "percentile":
+			pctCol = i
+		}
+	}
+	if cveCol < 0 || epssCol < 0 {
+		return fmt.Errorf("EPSS data is missing the cve or epss column")
+	}
+
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read EPSS data: %w", err)
+		}
+		if len(record) <= cveCol || len(record) <= epssCol {
+			continue
+		}
+
+		probability, err := strconv.ParseFloat(strings.TrimSpace(record[epssCol]), 64)
+		if err != nil {
+			continue
+		}
+		score := EPSSScore{Probability: probability}
+		if pctCol >= 0 && pctCol < len(record) {
+			score.Percentile, _ = strconv.ParseFloat(strings.TrimSpace(record[pctCol]), 64)
+		}
+		e.epss[strings.ToUpper(strings.TrimSpace(record[cveCol]))] = score
+	}
+
+	e.logger.WithField("cves", len(e.epss)).Info("EPSS data loaded")
+	return nil
+}
+
+// LoadKEV reads the CISA KEV catalog JSON feed (optionally gzipped)
+func (e *Enricher) LoadKEV(path string) error {
+	r, closer, err := openData(path)
+	if err != nil {
+		return fmt.Errorf("failed to open KEV catalog: %w", err)
+	}
+	defer closer()
+
+	var catalog struct {
+		CatalogVersion  string     `json:"catalogVersion"`
+		Vulnerabilities []KEVEntry `json:"vulnerabilities"`
+	}
+	if err := json.NewDecoder(r).Decode(&catalog); err != nil {
+		return fmt.Errorf("failed to parse KEV catalog: %w", err)
+	}
+
+	for _, entry := range catalog.Vulnerabilities {
+		e.kev[strings.ToUpper(strings.TrimSpace(entry.CVEID))] = entry
+	}
+
+	e.logger.WithFields(log.Fields{
+		"cves":    len(e.kev),
+		"version": catalog.CatalogVersion,
+	}).Info("KEV catalog loaded")
+	return nil
+}
+
+// openData opens a data file, transparently decompressing gzip
+func openData(path string) (io.Reader, func(), error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	br := bufio.NewReader(f)
+	magic, _ := br.Peek(2)
+	if len(magic) == 2 && magic[0] == 0x1f && magic[1] == 0x8b {
+		gz, err := gzip.NewReader(br)
+		if err != nil {
+			f.Close()
+			return nil, nil, err
+		}
+		return gz, func() { gz.Close(); f.Close() }, nil
+	}
+
+	return br, func() { f.Close() }, nil
+}
@@ -0,0 +1,130 @@
+// Package enrich adds exploitability context (CVSS, EPSS, CISA KEV) and a
+// risk score to findings that carry a CVE ID. All data is read from local
+// files so enrichment works offline.
+package enrich
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	pb "github.com/cloud-scan/cloudscan-orchestrator/generated/proto"
+	"github.com/cloud-scan/cloudscan-runner/internal/findings"
+	log "github.com/sirupsen/logrus"
+)
+
+// Risk score weights; the score ranges from 0 to 100
+const (
+	severityWeight = 60.0 // CVSS base score (or severity) out of 10
+	epssWeight     = 30.0 // EPSS exploitation probability
+	kevWeight      = 10.0 // Listed in the KEV catalog
+)
+
+// Enricher enriches findings from EPSS and KEV data
+type Enricher struct {
+	epss   map[string]EPSSScore
+	kev    map[string]KEVEntry
+	logger *log.Entry
+}
+
+// Stats summarizes an enrichment pass
+type Stats struct {
+	Enriched  int // Findings with a CVE ID
+	EPSS      int // Findings with an EPSS score
+	KEV       int // Findings listed in the KEV catalog
+	Escalated int // KEV findings raised to CRITICAL
+}
+
+// New creates an enricher without EPSS or KEV data
+func New() *Enricher {
+	return &Enricher{
+		epss:   make(map[string]EPSSScore),
+		kev:    make(map[string]KEVEntry),
+		logger: log.WithField("component", "enrich"),
+	}
+}
+
+// Enrich adds CVSS, EPSS and KEV details and a risk score to every finding
+// with a CVE ID, and escalates KEV-listed CVEs to CRITICAL
+func (e *Enricher) Enrich(results []*pb.Finding) Stats {
+	var stats Stats
+	for _, f := range results {
+		cve := strings.ToUpper(strings.TrimSpace(f.CveId))
+		if cve == "" {
+			continue
+		}
+		stats.Enriched++
+
+		epss, hasEPSS := e.epss[cve]
+		if hasEPSS {
+			stats.EPSS++
+			findings.SetDetail(f, findings.DetailEPSS, fmt.Sprintf("%.5f (percentile %.5f)", epss.Probability, epss.Percentile))
+		}
+
+		kev, inKEV := e.kev[cve]
+		if inKEV {
+			stats.KEV++
+			findings.SetDetail(f, findings.DetailKEV, kev.String())
+			if f.Severity != pb.Severity_CRITICAL {
+				note := fmt.Sprintf("%s -> %s (listed in CISA KEV catalog)", f.Severity, pb.Severity_CRITICAL)
+				if prior := findings.Detail(f, findings.DetailSeverityOverride); prior != "" {
+					note = prior + "; then " + note
+				}
+				findings.SetDetail(f, findings.DetailSeverityOverride, note)
+				f.Severity = pb.Severity_CRITICAL
+				stats.Escalated++
+			}
+		}
+
+		findings.SetDetail(f, findings.DetailRiskScore, strconv.FormatFloat(riskScore(f, epss.Probability, inKEV), 'f', 1, 64))
+	}
+	return stats
+}
+
+// riskScore combines the CVSS base score (CVSS v4, then v3, then the
+// severity), the EPSS probability and KEV listing into a 0-100 score
+func riskScore(f *pb.Finding, epss float64, inKEV bool) float64 {
+	base, ok := cvssScore(findings.Detail(f, findings.DetailCVSSv4))
+	if !ok {
+		base, ok = cvssScore(findings.Detail(f, findings.DetailCVSSv3))
+	}
+	if !ok {
+		base = severityScore(f.Severity)
+	}
+
+	score := base/10*severityWeight + epss*epssWeight
+	if inKEV {
+		score += kevWeight
+	}
+	return math.Round(math.Min(score, 100)*10) / 10
+}
+
+// cvssScore parses the score from a "<score> <vector>" CVSS detail
+func cvssScore(detail string) (float64, bool) {
+	fields := strings.Fields(detail)
+	if len(fields) == 0 {
+		return 0, false
+	}
+	score, err := strconv.ParseFloat(fields[0], 64)
+	if err != nil || score < 0 || score > 10 {
+		return 0, false
+	}
+	return score, true
+}
+
+// severityScore approximates a CVSS base score for a severity
+func severityScore(severity pb.Severity) float64 {
+	switch severity {
+	case pb.Severity_CRITICAL:
+		return 9.5
+	case pb.Severity_HIGH:
+		return 8.0
+	case pb.Severity_MEDIUM:
+		return 5.5
+	case pb.Severity_LOW:
+		return 2.0
+	default:
+		return 0
+	}
+}
@@ -0,0 +1,52 @@
+package findings
+
+import (
+	pb "github.com/cloud-scan/cloudscan-orchestrator/generated/proto"
+)
+
+// Diff classifies current findings against a baseline scan
+type Diff struct {
+	New       []*pb.Finding // Current findings absent from the baseline
+	Unchanged []*pb.Finding // Current findings also present in the baseline
+	Fixed     []*pb.Finding // Baseline findings no longer reported
+}
+
+// Compare matches current findings to baseline findings by fingerprint.
+// Identical fingerprints are matched one-to-one, so a second copy of a known
+// issue is still reported as new. Baseline findings outside inScope are
+// ignored rather than reported as fixed, since nothing checked them in this run.
+func Compare(current, baseline []*pb.Finding, sourceDir string, inScope func(*pb.Finding) bool) *Diff {
+	known := make(map[string][]*pb.Finding)
+	for _, f := range baseline {
+		if !inScope(f) {
+			continue
+		}
+		fp := Fingerprint(f, sourceDir)
+		known[fp] = append(known[fp], f)
+	}
+
+	diff := &Diff{}
+	for _, f := range current {
+		fp := Fingerprint(f, sourceDir)
+		if matches := known[fp]; len(matches) > 0 {
+			known[fp] = matches[1:]
+			diff.Unchanged = append(diff.Unchanged, f)
+			continue
+		}
+		diff.New = append(diff.New, f)
+	}
+
+	// Walk the baseline again to report fixed findings in a stable order
+	for _, f := range baseline {
+		if !inScope(f) {
+			continue
+		}
+		fp := Fingerprint(f, sourceDir)
+		if matches := known[fp]; len(matches) > 0 && matches[0] == f {
+			known[fp] = matches[1:]
+			diff.Fixed = append(diff.Fixed, f)
+		}
+	}
+
+	return diff
+}
@@ -0,0 +1,99 @@
+package findings
+
+import (
+	"strings"
+
+	pb "github.com/cloud-scan/cloudscan-orchestrator/generated/proto"
+)
+
+// detailsHeader introduces the structured details block that ends a
+// finding description
+const detailsHeader = "Details:"
+
+// Detail returns the value of a key in a finding's details block, or ""
+func Detail(f *pb.Finding, key string) string {
+	_, details := splitDetails(f.Description)
+	prefix := "- " + key + ": "
+	for _, line := range details {
+		if strings.HasPrefix(line, prefix) {
+			return strings.TrimPrefix(line, prefix)
+		}
+	}
+	return ""
+}
+
+// Details returns a finding's description without its details block, and
+// the details as a map
+func Details(f *pb.Finding) (string, map[string]string) {
+	body, lines := splitDetails(f.Description)
+	details := make(map[string]string, len(lines))
+	for _, line := range lines {
+		if key, value, ok := strings.Cut(strings.TrimPrefix(line, "- "), ": "); ok {
+			details[key] = value
+		}
+	}
+	return body, details
+}
+
+// SetDetail sets a key in a finding's details block, appending the block to
+// the description when it does not exist yet. Values are kept on one line.
+func SetDetail(f *pb.Finding, key, value string) {
+	value = strings.Join(strings.Fields(value), " ")
+	body, details := splitDetails(f.Description)
+
+	line := "- " + key + ": " + value
+	prefix := "- " + key + ": "
+	replaced := false
+	for i, existing := range details {
+		if strings.HasPrefix(existing, prefix) {
+			details[i] = line
+			replaced = true
+			break
+		}
+	}
+	if !replaced {
+		details = append(details, line)
+	}
+
+	block := detailsHeader + "\n" + strings.Join(details, "\n")
+	if body == "" {
+		f.Description = block
+		return
+	}
+	f.Description = body + "\n\n" + block
+}
+
+// AddSection adds a titled section to a finding's description, ahead of its
+// details block. The text is fenced and keeps its line breaks and
+// indentation, so code such as a suggested fix survives intact.
+func AddSection(f *pb.Finding, title, text string) {
+	body, details := splitDetails(f.Description)
+
+	section := title + ":\n```\n" + strings.TrimRight(text, "\n") + "\n```"
+	if body != "" {
+		section = body + "\n\n" + section
+	}
+	if len(details) == 0 {
+		f.Description = section
+		return
+	}
+	f.Description = section + "\n\n" + detailsHeader + "\n" + strings.Join(details, "\n")
+}
+
+// splitDetails separates a description from its trailing details block
+func splitDetails(description string) (string, []string) {
+	idx := strings.LastIndex(description, detailsHeader+"\n")
+	if idx < 0 || (idx > 0 && !strings.HasSuffix(description[:idx], "\n\n")) {
+		return strings.TrimRight(description, "\n"), nil
+	}
+
+	var details []string
+	for _, line := range strings.Split(description[idx+len(detailsHeader)+1:], "\n") {
+		if !strings.HasPrefix(line, "- ") {
+			// Not a details block after all
+			return strings.TrimRight(description, "\n"), nil
+		}
+		details = append(details, line)
+	}
+	return strings.TrimRight(description[:idx], "\n"), details
+}
@@ -0,0 +1,182 @@
+package findings
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	pb "github.com/cloud-scan/cloudscan-orchestrator/generated/proto"
+)
+
+// Detail keys shared by scanners and enrichment stages
+const (
+	// DetailLicenseCategory is the license category reported by the scanner
+	DetailLicenseCategory = "license_category"
+	// DetailSeverityOverride records an override of the scanner's severity
+	DetailSeverityOverride = "severity_override"
+	// DetailCVSSv3 and DetailCVSSv4 hold "<score> <vector>" of the vulnerability
+	DetailCVSSv3 = "cvss_v3"
+	DetailCVSSv4 = "cvss_v4"
+	// DetailEPSS is the EPSS exploitation probability and percentile
+	DetailEPSS = "epss"
+	// DetailKEV records a CISA Known Exploited Vulnerabilities listing
+	DetailKEV = "kev"
+	// DetailRiskScore is the computed 0-100 risk score
+	DetailRiskScore = "risk_score"
+
+	// DetailColumn, DetailEndLine and DetailEndColumn complete the location
+	// started by the finding's line number
+	DetailColumn    = "column"
+	DetailEndLine   = "end_line"
+	DetailEndColumn = "end_column"
+	// DetailCWE lists the CWEs after the first, which is the finding's CweId
+	DetailCWE = "cwe"
+	// DetailOWASP lists the OWASP Top 10 categories of a rule
+	DetailOWASP = "owasp"
+	// DetailConfidence is the scanner's confidence in the finding
+	DetailConfidence = "confidence"
+	// DetailPackage, DetailInstalledVersion and DetailFixedVersion describe
+	// the vulnerable package of an SCA finding
+	DetailPackage          = "package"
+	DetailInstalledVersion = "installed_version"
+	DetailFixedVersion     = "fixed_version"
+	// DetailVerified records whether a secret was verified as live
+	DetailVerified = "verified"
+	// DetailSnippetLines is the "<first>-<last>" line range of a snippet
+	// extracted from the workspace, context lines included
+	DetailSnippetLines = "snippet_lines"
+	// DetailBlameCommit, DetailBlameAuthor and DetailBlameDate identify the
+	// commit that last changed the finding's line
+	DetailBlameCommit = "blame_commit"
+	DetailBlameAuthor = "blame_author"
+	DetailBlameDate   = "blame_date"
+	// DetailIntroducedCommit, DetailIntroducedAuthor and DetailIntroducedDate
+	// identify the commit that introduced the line's content
+	DetailIntroducedCommit = "introduced_commit"
+	DetailIntroducedAuthor = "introduced_author"
+	DetailIntroducedDate   = "introduced_date"
+	// DetailBlameTruncated marks blame limited by a shallow clone
+	DetailBlameTruncated = "blame_truncated"
+	// DetailCommit, DetailCommitAuthor and DetailCommitDate identify the
+	// commit a finding from a git history scan was found in
+	DetailCommit       = "commit"
+	DetailCommitAuthor = "commit_author"
+	DetailCommitDate   = "commit_date"
+)
+
+// SectionFix titles the replacement code suggested by the scanner
+const SectionFix = "Suggested fix"
+
+var cweNumber = regexp.MustCompile(`(?i)CWE-?(\d+)`)
+
+// RuleID returns the rule that produced a finding. Scanners report it as
+// the finding title.
+func RuleID(f *pb.Finding) string {
+	return strings.TrimSpace(f.Title)
+}
+
+// Detector returns the secret detector that produced a SECRETS finding, or ""
+func Detector(f *pb.Finding) string {
+	if f.ScanType != pb.ScanType_SECRETS {
+		return ""
+	}
+	return strings.TrimSpace(strings.TrimPrefix(f.Title, "Secret detected:"))
+}
+
+// License returns the license named by a LICENSE finding, or ""
+func License(f *pb.Finding) string {
+	if f.ScanType != pb.ScanType_LICENSE {
+		return ""
+	}
+	return strings.TrimSpace(strings.TrimPrefix(f.Title, "License:"))
+}
+
+// Package returns the package name and version of an SCA finding, which
+// scanners report in the title as "<id> in <name>@<version>"
+func Package(f *pb.Finding) (string, string) {
+	if f.ScanType != pb.ScanType_SCA {
+		return "", ""
+	}
+	idx := strings.LastIndex(f.Title, " in ")
+	if idx < 0 {
+		return "", ""
+	}
+	pkg := strings.TrimSpace(f.Title[idx+len(" in "):])
+	// Scoped npm packages start with "@", so split at the last one
+	if at := strings.LastIndex(pkg, "@"); at > 0 {
+		return pkg[:at], pkg[at+1:]
+	}
+	return pkg, ""
+}
+
+// MatchedSnippet returns the lines of a finding's snippet that the scanner
+// matched, without the context lines added from the workspace
+func MatchedSnippet(f *pb.Finding) string {
+	var first, last int
+	if _, err := fmt.Sscanf(Detail(f, DetailSnippetLines), "%d-%d", &first, &last); err != nil || f.LineNumber <= 0 {
+		return f.CodeSnippet
+	}
+
+	start := int(f.LineNumber)
+	end := start
+	if n, err := strconv.Atoi(Detail(f, DetailEndLine)); err == nil && n > start {
+		end = n
+	}
+
+	lines := strings.Split(f.CodeSnippet, "\n")
+	from, to := start-first, end-first+1
+	if from < 0 || from >= len(lines) {
+		return f.CodeSnippet
+	}
+	if to > len(lines) {
+		to = len(lines)
+	}
+	return strings.Join(lines[from:to], "\n")
+}
+
+// FixAvailable reports whether an SCA finding names a fixed version
+func FixAvailable(f *pb.Finding) bool {
+	return Detail(f, DetailFixedVersion) != ""
+}
+
+// CWE returns a finding's CWE in "CWE-<n>" form, or "" when it has none
+func CWE(f *pb.Finding) string {
+	m := cweNumber.FindStringSubmatch(f.CweId)
+	if m == nil {
+		return ""
+	}
+	return "CWE-" + m[1]
+}
+
+// AddReferences appends reference URLs to a finding, skipping blanks and
+// references it already has
+func AddReferences(f *pb.Finding, refs ...string) {
+	for _, ref := range refs {
+		ref = strings.TrimSpace(ref)
+		if ref == "" || slices.Contains(f.References, ref) {
+			continue
+		}
+		f.References = append(f.References, ref)
+	}
+}
+
+// SetListDetail sets a detail to a comma-separated list, skipping empty lists
+func SetListDetail(f *pb.Finding, key string, values []string) {
+	var kept []string
+	for _, v := range values {
+		if v = strings.TrimSpace(v); v != "" {
+			kept = append(kept, v)
+		}
+	}
+	if len(kept) > 0 {
+		SetDetail(f, key, strings.Join(kept, ", "))
+	}
+}
+
+// FromHistory reports whether a finding was found in a past commit rather
+// than in the checked-out tree
+func FromHistory(f *pb.Finding) bool {
+	return Detail(f, DetailCommit) != ""
+}
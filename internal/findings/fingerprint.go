@@ -0,0 +1,49 @@
+// Package findings identifies findings across scans.
+package findings
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"path/filepath"
+	"strings"
+
+	pb "github.com/cloud-scan/cloudscan-orchestrator/generated/proto"
+)
+
+// Fingerprint returns an identity for a finding that survives line shifts.
+// It combines the scan type, rule ID, source-relative file path and the code
+// snippet with whitespace normalized; line numbers and context lines added
+// to the snippet are deliberately ignored.
+func Fingerprint(f *pb.Finding, sourceDir string) string {
+	h := sha256.New()
+	for _, part := range []string{
+		f.ScanType.String(),
+		RuleID(f),
+		NormalizePath(f.FilePath, sourceDir),
+		NormalizeSnippet(MatchedSnippet(f)),
+	} {
+		h.Write([]byte(part))
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// NormalizePath returns path relative to sourceDir with forward slashes
+func NormalizePath(path, sourceDir string) string {
+	if path == "" {
+		return ""
+	}
+	if sourceDir != "" && filepath.IsAbs(path) {
+		if rel, err := filepath.Rel(sourceDir, path); err == nil && rel != ".." && !strings.HasPrefix(rel, "../") {
+			path = rel
+		}
+	}
+	path = filepath.ToSlash(filepath.Clean(path))
+	return strings.TrimPrefix(path, "./")
+}
+
+// NormalizeSnippet collapses whitespace runs so re-indentation and line
+// ending changes do not alter the fingerprint
+func NormalizeSnippet(snippet string) string {
+	return strings.Join(strings.Fields(snippet), " ")
+}
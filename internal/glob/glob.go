@@ -0,0 +1,126 @@
+// Package glob matches slash-separated paths against glob patterns with
+// "**" support.
+package glob
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Pattern is a compiled path glob
+type Pattern struct {
+	source string
+	re     *regexp.Regexp
+}
+
+// Compile compiles a glob pattern. "*" matches within one path segment,
+// "?" matches one character other than "/", "[...]" is a character class and
+// "**" matches any number of segments. A pattern without a "/" matches the
+// base name at any depth, a leading "/" anchors the pattern to the root and a
+// trailing "/" matches everything below a directory.
+func Compile(pattern string) (*Pattern, error) {
+	p := strings.TrimSpace(pattern)
+	if p == "" {
+		return nil, fmt.Errorf("empty glob pattern")
+	}
+
+	if strings.HasSuffix(p, "/") {
+		p += "**"
+	}
+	if strings.HasPrefix(p, "/") {
+		p = strings.TrimLeft(p, "/")
+	} else if !strings.Contains(p, "/") {
+		p = "**/" + p
+	}
+
+	return compile(pattern, p, "[^/]", false)
+}
+
+// CompileName compiles a case-insensitive glob for names such as rule IDs
+// or license identifiers, where "*" matches any run of characters
+func CompileName(pattern string) (*Pattern, error) {
+	p := strings.TrimSpace(pattern)
+	if p == "" {
+		return nil, fmt.Errorf("empty glob pattern")
+	}
+	return compile(pattern, p, ".", true)
+}
+
+// compile translates a glob to a regular expression; any is the expression
+// matched by "?" and repeated by "*"
+func compile(pattern, p, any string, foldCase bool) (*Pattern, error) {
+	var b strings.Builder
+	if foldCase {
+		b.WriteString("(?i)")
+	}
+	b.WriteString("^")
+	for i := 0; i < len(p); i++ {
+		c := p[i]
+		switch c {
+		case '*':
+			if i+1 < len(p) && p[i+1] == '*' {
+				i++
+				if i+1 < len(p) && p[i+1] == '/' {
+					// "**/" matches zero or more leading directories
+					i++
+					b.WriteString("(?:.*/)?")
+				} else {
+					b.WriteString(".*")
+				}
+			} else {
+				b.WriteString(any + "*")
+			}
+		case '?':
+			b.WriteString(any)
+		case '[':
+			end := strings.IndexByte(p[i+1:], ']')
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated character class in %q", pattern)
+			}
+			class := p[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			b.WriteString("[" + strings.ReplaceAll(class, `\`, `\\`) + "]")
+			i += end + 1
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	b.WriteString("$")
+
+	re, err := regexp.Compile(b.String())
+	if err != nil {
+		return nil, fmt.Errorf("invalid glob %q: %w", pattern, err)
+	}
+	return &Pattern{source: pattern, re: re}, nil
+}
+
+// MustCompile compiles a glob pattern and panics if it is invalid
+func MustCompile(pattern string) *Pattern {
+	p, err := Compile(pattern)
+	if err != nil {
+		panic(err)
+	}
+	return p
+}
+
+// Match reports whether a slash-separated path matches the pattern
+func (p *Pattern) Match(path string) bool {
+	return p.re.MatchString(strings.TrimPrefix(path, "./"))
+}
+
+// String returns the pattern source
+func (p *Pattern) String() string {
+	return p.source
+}
+
+// Match reports whether path matches pattern
+func Match(pattern, path string) (bool, error) {
+	p, err := Compile(pattern)
+	if err != nil {
+		return false, err
+	}
+	return p.Match(path), nil
+}
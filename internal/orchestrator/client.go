@@ -9,9 +9,11 @@ import (
 	"github.com/google/uuid"
 	log "github.com/sirupsen/logrus"
 	"google.golang.org/grpc"
-	"google.golang.org/grpc/credentials/insecure"
 )
 
+// findingsPageSize is the number of findings requested per GetFindings call
+const findingsPageSize = 500
+
 // Client wraps the orchestrator gRPC client
 type Client struct {
 	conn   *grpc.ClientConn
@@ -19,19 +21,29 @@ type Client struct {
 	logger *log.Entry
 }
 
-// NewClient creates a new orchestrator client
-func NewClient(endpoint string) (*Client, error) {
+// NewClient creates a new orchestrator client. Connecting gives up after
+// 10 seconds, or earlier when ctx ends.
+func NewClient(ctx context.Context, endpoint string, options Options) (*Client, error) {
 	logger := log.WithField("component", "orchestrator-client")
-	logger.WithField("endpoint", endpoint).Info("Connecting to orchestrator")
+	logger.WithFields(log.Fields{
+		"endpoint": endpoint,
+		"tls":      options.TLS,
+		"mtls":     options.CertFile != "",
+		"token":    options.TokenFile != "",
+	}).Info("Connecting to orchestrator")
 
 	// Configure gRPC dial options
-	opts := []grpc.DialOption{
-		grpc.WithTransportCredentials(insecure.NewCredentials()),
-		grpc.WithBlock(),
+	opts, err := options.dialOptions(logger)
+	if err != nil {
+		return nil, err
 	}
+	opts = append(opts,
+		grpc.WithBlock(),
+		grpc.WithReturnConnectionError(), // Report TLS failures rather than a timeout
+	)
 
-	// Connect with timeout
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	// Connect with timeout, bounded by ctx as well
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
 	defer cancel()
 
 	conn, err := grpc.DialContext(ctx, endpoint, opts...)
@@ -102,6 +114,44 @@ func (c *Client) CreateFindings(ctx context.Context, scanID uuid.UUID, findings
 	return nil
 }
 
+// GetFindings pages through all findings recorded for a scan
+func (c *Client) GetFindings(ctx context.Context, scanID uuid.UUID) ([]*pb.Finding, error) {
+	c.logger.WithField("scan_id", scanID).Debug("Fetching findings")
+
+	var findings []*pb.Finding
+	seenTokens := make(map[string]bool)
+	pageToken := ""
+
+	for {
+		resp, err := c.client.GetFindings(ctx, &pb.GetFindingsRequest{
+			ScanId:    scanID.String(),
+			PageSize:  findingsPageSize,
+			PageToken: pageToken,
+		})
+		if err != nil {
+			c.logger.WithError(err).Error("Failed to get findings")
+			return nil, fmt.Errorf("failed to get findings: %w", err)
+		}
+
+		findings = append(findings, resp.Findings...)
+
+		pageToken = resp.NextPageToken
+		if pageToken == "" {
+			break
+		}
+		if seenTokens[pageToken] {
+			return nil, fmt.Errorf("failed to get findings: page token %q repeated", pageToken)
+		}
+		seenTokens[pageToken] = true
+	}
+
+	c.logger.WithFields(log.Fields{
+		"scan_id": scanID,
+		"count":   len(findings),
+	}).Info("Findings fetched successfully")
+	return findings, nil
+}
+
 // UpdateFindingsCount updates the total findings count for a scan
 func (c *Client) UpdateFindingsCount(ctx context.Context, scanID uuid.UUID, count int32) error {
 	c.logger.WithFields(log.Fields{
@@ -0,0 +1,246 @@
+package orchestrator
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+)
+
+// Metadata keys identifying the tenant of every request
+const (
+	organizationIDKey = "x-organization-id"
+	projectIDKey      = "x-project-id"
+)
+
+// Options secures the connection to the orchestrator. The zero value dials
+// without TLS or authentication.
+type Options struct {
+	// TLS enables transport security. The server certificate is verified
+	// against CAFile, or the system roots when it is empty; ServerName
+	// overrides the name it is verified for.
+	TLS        bool
+	CAFile     string
+	ServerName string
+
+	// CertFile and KeyFile hold a client certificate for mutual TLS. They
+	// are re-read when they change, so rotated certificates are presented
+	// on new connections.
+	CertFile string
+	KeyFile  string
+
+	// TokenFile holds a bearer token sent with every request, such as a
+	// projected service account token. It is re-read when it changes.
+	TokenFile string
+
+	// OrganizationID and ProjectID are sent as request metadata so the
+	// orchestrator can enforce tenancy
+	OrganizationID string
+	ProjectID      string
+}
+
+// dialOptions returns the gRPC options securing and authenticating the
+// connection
+func (o Options) dialOptions(logger *log.Entry) ([]grpc.DialOption, error) {
+	var opts []grpc.DialOption
+
+	if o.TLS {
+		tlsConfig, err := o.tlsConfig(logger)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, grpc.WithTransportCredentials(credentials.NewTLS(tlsConfig)))
+	} else {
+		opts = append(opts, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	}
+
+	if o.TokenFile != "" {
+		token := &fileToken{path: o.TokenFile, logger: logger}
+		// Fail at startup rather than on the first request
+		if _, err := token.get(); err != nil {
+			return nil, err
+		}
+		opts = append(opts, grpc.WithPerRPCCredentials(token))
+	}
+
+	var tenant []string
+	if o.OrganizationID != "" {
+		tenant = append(tenant, organizationIDKey, o.OrganizationID)
+	}
+	if o.ProjectID != "" {
+		tenant = append(tenant, projectIDKey, o.ProjectID)
+	}
+	if len(tenant) > 0 {
+		opts = append(opts, grpc.WithChainUnaryInterceptor(
+			func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, callOpts ...grpc.CallOption) error {
+				return invoker(metadata.AppendToOutgoingContext(ctx, tenant...), method, req, reply, cc, callOpts...)
+			}))
+	}
+
+	return opts, nil
+}
+
+// tlsConfig builds the TLS configuration of the connection
+func (o Options) tlsConfig(logger *log.Entry) (*tls.Config, error) {
+	config := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		ServerName: o.ServerName,
+	}
+
+	if o.CAFile != "" {
+		pem, err := os.ReadFile(o.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read orchestrator CA bundle: %w", err)
+		}
+		config.RootCAs = x509.NewCertPool()
+		if !config.RootCAs.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in orchestrator CA bundle %s", o.CAFile)
+		}
+	}
+
+	if o.CertFile != "" || o.KeyFile != "" {
+		if o.CertFile == "" || o.KeyFile == "" {
+			return nil, fmt.Errorf("a client certificate requires both a certificate and a key file")
+		}
+		keyPair := &keyPairReloader{certFile: o.CertFile, keyFile: o.KeyFile, logger: logger}
+		if _, err := keyPair.get(); err != nil {
+			return nil, err
+		}
+		config.GetClientCertificate = func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			return keyPair.get()
+		}
+	}
+
+	return config, nil
+}
+
+// fileToken is a bearer token read from a file and re-read whenever the
+// file changes, as Kubernetes does when it rotates projected tokens
+type fileToken struct {
+	path   string
+	logger *log.Entry
+
+	mu      sync.Mutex
+	token   string
+	version fileVersion
+}
+
+// GetRequestMetadata adds the token to a request
+func (t *fileToken) GetRequestMetadata(ctx context.Context, uri ...string) (map[string]string, error) {
+	token, err := t.get()
+	if err != nil {
+		return nil, err
+	}
+	return map[string]string{"authorization": "Bearer " + token}, nil
+}
+
+// RequireTransportSecurity keeps the token off plaintext connections
+func (t *fileToken) RequireTransportSecurity() bool {
+	return true
+}
+
+// get returns the current token, re-reading the file if it changed. A
+// token that cannot be re-read is replaced only once a new one is readable.
+func (t *fileToken) get() (string, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	version, err := statVersion(t.path)
+	if err == nil && version == t.version {
+		return t.token, nil
+	}
+
+	var token string
+	if err == nil {
+		var data []byte
+		data, err = os.ReadFile(t.path)
+		token = strings.TrimSpace(string(data))
+		if err == nil && token == "" {
+			err = fmt.Errorf("token file is empty")
+		}
+	}
+	if err != nil {
+		if t.token != "" {
+			t.logger.WithError(err).Warn("Failed to reload orchestrator token, using the previous one")
+			return t.token, nil
+		}
+		return "", fmt.Errorf("failed to read orchestrator token: %w", err)
+	}
+
+	if t.token != "" {
+		t.logger.Info("Reloaded rotated orchestrator token")
+	}
+	t.token = token
+	t.version = version
+	return t.token, nil
+}
+
+// keyPairReloader loads a client certificate, reloading it when either
+// file changes
+type keyPairReloader struct {
+	certFile string
+	keyFile  string
+	logger   *log.Entry
+
+	mu          sync.Mutex
+	cert        *tls.Certificate
+	certVersion fileVersion
+	keyVersion  fileVersion
+}
+
+// get returns the current certificate. While a rotation is in progress the
+// files may not match; the previous certificate is kept until they do.
+func (k *keyPairReloader) get() (*tls.Certificate, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	certVersion, certErr := statVersion(k.certFile)
+	keyVersion, keyErr := statVersion(k.keyFile)
+	if certErr == nil && keyErr == nil && certVersion == k.certVersion && keyVersion == k.keyVersion {
+		return k.cert, nil
+	}
+
+	cert, err := tls.LoadX509KeyPair(k.certFile, k.keyFile)
+	if err != nil {
+		if k.cert != nil {
+			k.logger.WithError(err).Warn("Failed to reload orchestrator client certificate, using the previous one")
+			return k.cert, nil
+		}
+		return nil, fmt.Errorf("failed to load orchestrator client certificate: %w", err)
+	}
+
+	if k.cert != nil {
+		k.logger.Info("Reloaded rotated orchestrator client certificate")
+	}
+	k.cert = &cert
+	k.certVersion = certVersion
+	k.keyVersion = keyVersion
+	return k.cert, nil
+}
+
+// fileVersion identifies the content of a file by its size and
+// modification time
+type fileVersion struct {
+	size    int64
+	modTime time.Time
+}
+
+// statVersion returns the version of a file, following symlinks such as
+// those swapped by Kubernetes when it updates mounted secrets
+func statVersion(path string) (fileVersion, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return fileVersion{}, err
+	}
+	return fileVersion{size: info.Size(), modTime: info.ModTime()}, nil
+}
@@ -0,0 +1,217 @@
+// Package overrides re-maps finding severities according to a table
+// maintained by the security team.
+package overrides
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	pb "github.com/cloud-scan/cloudscan-orchestrator/generated/proto"
+	"github.com/cloud-scan/cloudscan-runner/internal/findings"
+	"github.com/cloud-scan/cloudscan-runner/internal/glob"
+	"github.com/cloud-scan/cloudscan-runner/internal/scanners"
+	"gopkg.in/yaml.v3"
+)
+
+// Table is an ordered list of severity overrides; the first match wins
+type Table struct {
+	Overrides []*Override `yaml:"overrides"`
+
+	source string
+}
+
+// Override sets the severity of findings matching all of its criteria.
+// Every criterion except scan_type is a case-insensitive glob.
+type Override struct {
+	Scanner         string `yaml:"scanner"`
+	ScanType        string `yaml:"scan_type"`
+	RuleID          string `yaml:"rule_id"`
+	CWE             string `yaml:"cwe"`
+	Detector        string `yaml:"detector"`
+	LicenseCategory string `yaml:"license_category"`
+
+	// Severity is the new severity (CRITICAL, HIGH, MEDIUM, LOW or INFO)
+	Severity string `yaml:"severity"`
+
+	// Reason is recorded with every override applied
+	Reason string `yaml:"reason"`
+
+	line            int
+	scanType        pb.ScanType
+	severity        pb.Severity
+	scanner         *glob.Pattern
+	ruleID          *glob.Pattern
+	cwe             *glob.Pattern
+	detector        *glob.Pattern
+	licenseCategory *glob.Pattern
+}
+
+// Load reads and validates an override table
+func Load(path string) (*Table, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read severity overrides: %w", err)
+	}
+	t, err := Parse(data)
+	if err != nil {
+		return nil, err
+	}
+	t.source = path
+	return t, nil
+}
+
+// Parse parses and validates an override table
+func Parse(data []byte) (*Table, error) {
+	t := &Table{source: "severity overrides"}
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(t); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("failed to parse severity overrides: %w", err)
+	}
+
+	var root yaml.Node
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		return nil, fmt.Errorf("failed to parse severity overrides: %w", err)
+	}
+	lines := overrideLines(&root)
+
+	var errs []error
+	for i, o := range t.Overrides {
+		if o == nil {
+			errs = append(errs, fmt.Errorf("override %d is empty", i+1))
+			continue
+		}
+		if i < len(lines) {
+			o.line = lines[i]
+		}
+		if err := o.validate(); err != nil {
+			errs = append(errs, fmt.Errorf("line %d: %w", o.line, err))
+		}
+	}
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+
+	return t, nil
+}
+
+// overrideLines returns the line of every item of the overrides sequence
+func overrideLines(root *yaml.Node) []int {
+	if root.Kind != yaml.DocumentNode || len(root.Content) == 0 || root.Content[0].Kind != yaml.MappingNode {
+		return nil
+	}
+	mapping := root.Content[0]
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value != "overrides" {
+			continue
+		}
+		var lines []int
+		for _, item := range mapping.Content[i+1].Content {
+			lines = append(lines, item.Line)
+		}
+		return lines
+	}
+	return nil
+}
+
+// validate resolves enum values and compiles the globs
+func (o *Override) validate() error {
+	value, ok := pb.Severity_value[strings.ToUpper(strings.TrimSpace(o.Severity))]
+	if !ok || pb.Severity(value) == pb.Severity_SEVERITY_UNSPECIFIED {
+		return fmt.Errorf("unknown severity %q", o.Severity)
+	}
+	o.severity = pb.Severity(value)
+
+	if o.Scanner == "" && o.ScanType == "" && o.RuleID == "" && o.CWE == "" && o.Detector == "" && o.LicenseCategory == "" {
+		return fmt.Errorf("override needs at least one of scanner, scan_type, rule_id, cwe, detector or license_category")
+	}
+
+	if o.ScanType != "" {
+		scanType, ok := scanners.ParseScanType(o.ScanType)
+		if !ok {
+			return fmt.Errorf("unknown scan_type %q", o.ScanType)
+		}
+		o.scanType = scanType
+	}
+
+	for _, field := range []struct {
+		pattern string
+		target  **glob.Pattern
+	}{
+		{o.Scanner, &o.scanner},
+		{o.RuleID, &o.ruleID},
+		{o.CWE, &o.cwe},
+		{o.Detector, &o.detector},
+		{o.LicenseCategory, &o.licenseCategory},
+	} {
+		if field.pattern == "" {
+			continue
+		}
+		p, err := glob.CompileName(field.pattern)
+		if err != nil {
+			return err
+		}
+		*field.target = p
+	}
+
+	return nil
+}
+
+// Matches reports whether a finding reported by scanner matches the override
+func (o *Override) Matches(scanner string, f *pb.Finding) bool {
+	if o.scanType != pb.ScanType_SCAN_TYPE_UNSPECIFIED && f.ScanType != o.scanType {
+		return false
+	}
+	for _, check := range []struct {
+		pattern *glob.Pattern
+		value   string
+	}{
+		{o.scanner, scanner},
+		{o.ruleID, findings.RuleID(f)},
+		{o.cwe, findings.CWE(f)},
+		{o.detector, findings.Detector(f)},
+		{o.licenseCategory, findings.Detail(f, findings.DetailLicenseCategory)},
+	} {
+		if check.pattern != nil && (check.value == "" || !check.pattern.Match(check.value)) {
+			return false
+		}
+	}
+	return true
+}
+
+// Apply overrides the severity of findings reported by scanner and records
+// each change in the finding's details. It returns the number of findings
+// whose severity changed.
+func (t *Table) Apply(scanner string, results []*pb.Finding) int {
+	changed := 0
+	for _, f := range results {
+		o := t.match(scanner, f)
+		if o == nil || o.severity == f.Severity {
+			continue
+		}
+
+		note := fmt.Sprintf("%s -> %s (%s:%d)", f.Severity, o.severity, t.source, o.line)
+		if o.Reason != "" {
+			note += ": " + o.Reason
+		}
+		findings.SetDetail(f, findings.DetailSeverityOverride, note)
+
+		f.Severity = o.severity
+		changed++
+	}
+	return changed
+}
+
+// match returns the first override matching a finding
+func (t *Table) match(scanner string, f *pb.Finding) *Override {
+	for _, o := range t.Overrides {
+		if o.Matches(scanner, f) {
+			return o
+		}
+	}
+	return nil
+}
@@ -0,0 +1,230 @@
+// Package policy evaluates scan findings against pass/fail rules.
+package policy
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	pb "github.com/cloud-scan/cloudscan-orchestrator/generated/proto"
+	"github.com/cloud-scan/cloudscan-runner/internal/findings"
+	"github.com/cloud-scan/cloudscan-runner/internal/glob"
+	"github.com/cloud-scan/cloudscan-runner/internal/scanners"
+	"gopkg.in/yaml.v3"
+)
+
+// maxExamples bounds the findings listed per violation
+const maxExamples = 5
+
+// Policy is a set of rules a scan must satisfy
+type Policy struct {
+	Rules []*Rule `yaml:"rules"`
+}
+
+// Rule fails the scan when more than Max findings match all of its criteria
+type Rule struct {
+	// Name identifies the rule in verdicts
+	Name string `yaml:"name"`
+
+	// ScanType restricts the rule to one scan type (e.g. "sca")
+	ScanType string `yaml:"scan_type"`
+
+	// MinSeverity counts findings at or above this severity
+	MinSeverity string `yaml:"min_severity"`
+
+	// FixAvailable restricts the rule to findings with (true) or without
+	// (false) a known fixed version
+	FixAvailable *bool `yaml:"fix_available"`
+
+	// DenyLicenses are license globs (e.g. "GPL-3.0*"); setting them limits
+	// the rule to LICENSE findings for those licenses
+	DenyLicenses []string `yaml:"deny_licenses"`
+
+	// Max is the number of matching findings tolerated (default 0)
+	Max int `yaml:"max"`
+
+	scanType    pb.ScanType
+	minSeverity pb.Severity
+	licenses    []*glob.Pattern
+}
+
+// Load reads and validates a policy file
+func Load(path string) (*Policy, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read policy file: %w", err)
+	}
+	return Parse(data)
+}
+
+// Parse parses and validates a policy
+func Parse(data []byte) (*Policy, error) {
+	p := &Policy{}
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(p); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("failed to parse policy: %w", err)
+	}
+
+	var errs []error
+	for i, r := range p.Rules {
+		if r == nil {
+			errs = append(errs, fmt.Errorf("rule %d is empty", i+1))
+			continue
+		}
+		if r.Name == "" {
+			r.Name = fmt.Sprintf("rule-%d", i+1)
+		}
+		if err := r.validate(); err != nil {
+			errs = append(errs, fmt.Errorf("rule %q: %w", r.Name, err))
+		}
+	}
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+
+	return p, nil
+}
+
+// validate resolves enum values and compiles license globs
+func (r *Rule) validate() error {
+	if r.Max < 0 {
+		return fmt.Errorf("max must not be negative")
+	}
+
+	if r.ScanType != "" {
+		scanType, ok := scanners.ParseScanType(r.ScanType)
+		if !ok {
+			return fmt.Errorf("unknown scan_type %q", r.ScanType)
+		}
+		r.scanType = scanType
+	}
+
+	if r.MinSeverity != "" {
+		value, ok := pb.Severity_value[strings.ToUpper(strings.TrimSpace(r.MinSeverity))]
+		if !ok || pb.Severity(value) == pb.Severity_SEVERITY_UNSPECIFIED {
+			return fmt.Errorf("unknown min_severity %q", r.MinSeverity)
+		}
+		r.minSeverity = pb.Severity(value)
+	}
+
+	for _, license := range r.DenyLicenses {
+		pattern, err := glob.CompileName(license)
+		if err != nil {
+			return err
+		}
+		r.licenses = append(r.licenses, pattern)
+	}
+	if len(r.licenses) > 0 {
+		if r.scanType != pb.ScanType_SCAN_TYPE_UNSPECIFIED && r.scanType != pb.ScanType_LICENSE {
+			return fmt.Errorf("deny_licenses requires scan_type license")
+		}
+		r.scanType = pb.ScanType_LICENSE
+	}
+
+	return nil
+}
+
+// Matches reports whether a finding counts against the rule
+func (r *Rule) Matches(f *pb.Finding) bool {
+	if r.scanType != pb.ScanType_SCAN_TYPE_UNSPECIFIED && f.ScanType != r.scanType {
+		return false
+	}
+	// Severity enum values grow as severity drops (CRITICAL=1 ... INFO=5)
+	if r.minSeverity != pb.Severity_SEVERITY_UNSPECIFIED &&
+		(f.Severity == pb.Severity_SEVERITY_UNSPECIFIED || f.Severity > r.minSeverity) {
+		return false
+	}
+	if r.FixAvailable != nil && findings.FixAvailable(f) != *r.FixAvailable {
+		return false
+	}
+	if len(r.licenses) > 0 {
+		license := findings.License(f)
+		if license == "" {
+			return false
+		}
+		denied := false
+		for _, pattern := range r.licenses {
+			if pattern.Match(license) {
+				denied = true
+				break
+			}
+		}
+		if !denied {
+			return false
+		}
+	}
+	return true
+}
+
+// Verdict is the outcome of evaluating a policy
+type Verdict struct {
+	Passed     bool        `json:"passed"`
+	Violations []Violation `json:"violations"`
+}
+
+// Violation reports a rule whose tolerance was exceeded
+type Violation struct {
+	Rule     string   `json:"rule"`
+	Count    int      `json:"count"`
+	Max      int      `json:"max"`
+	Examples []string `json:"examples"`
+}
+
+// Evaluate checks findings against every rule
+func (p *Policy) Evaluate(results []*pb.Finding) *Verdict {
+	verdict := &Verdict{Passed: true, Violations: []Violation{}}
+
+	for _, rule := range p.Rules {
+		count := 0
+		var examples []string
+		for _, f := range results {
+			if !rule.Matches(f) {
+				continue
+			}
+			count++
+			if len(examples) < maxExamples {
+				examples = append(examples, describe(f))
+			}
+		}
+
+		if count > rule.Max {
+			verdict.Passed = false
+			verdict.Violations = append(verdict.Violations, Violation{
+				Rule:     rule.Name,
+				Count:    count,
+				Max:      rule.Max,
+				Examples: examples,
+			})
+		}
+	}
+
+	return verdict
+}
+
+// Summary describes the verdict in one line
+func (v *Verdict) Summary() string {
+	if v.Passed {
+		return "Policy gate passed"
+	}
+	parts := make([]string, 0, len(v.Violations))
+	for _, violation := range v.Violations {
+		parts = append(parts, fmt.Sprintf("%s (%d found, %d allowed)", violation.Rule, violation.Count, violation.Max))
+	}
+	return "Policy gate failed: " + strings.Join(parts, "; ")
+}
+
+// describe names a finding in violation examples
+func describe(f *pb.Finding) string {
+	s := fmt.Sprintf("[%s] %s", f.Severity, f.Title)
+	if f.FilePath != "" {
+		s += " at " + f.FilePath
+		if f.LineNumber > 0 {
+			s += fmt.Sprintf(":%d", f.LineNumber)
+		}
+	}
+	return s
+}
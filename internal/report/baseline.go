@@ -0,0 +1,82 @@
+package report
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	pb "github.com/cloud-scan/cloudscan-orchestrator/generated/proto"
+	"github.com/cloud-scan/cloudscan-runner/internal/findings"
+)
+
+// BaselineFileName is the name of the baseline summary written to the results directory
+const BaselineFileName = "baseline-summary.json"
+
+// BaselineSummary describes how a scan's findings compare to a baseline scan
+type BaselineSummary struct {
+	ScanID         string          `json:"scan_id"`
+	BaselineScanID string          `json:"baseline_scan_id"`
+	New            int             `json:"new"`
+	Unchanged      int             `json:"unchanged"`
+	Fixed          int             `json:"fixed"`
+	FixedFindings  []BaselineEntry `json:"fixed_findings"`
+}
+
+// BaselineEntry identifies a finding in the baseline summary
+type BaselineEntry struct {
+	Fingerprint string `json:"fingerprint"`
+	ScanType    string `json:"scan_type"`
+	Severity    string `json:"severity"`
+	RuleID      string `json:"rule_id"`
+	FilePath    string `json:"file_path,omitempty"`
+	LineNumber  int32  `json:"line_number,omitempty"`
+}
+
+// NewBaselineSummary summarizes a baseline comparison
+func NewBaselineSummary(scanID, baselineScanID, sourceDir string, diff *findings.Diff) *BaselineSummary {
+	summary := &BaselineSummary{
+		ScanID:         scanID,
+		BaselineScanID: baselineScanID,
+		New:            len(diff.New),
+		Unchanged:      len(diff.Unchanged),
+		Fixed:          len(diff.Fixed),
+		FixedFindings:  make([]BaselineEntry, 0, len(diff.Fixed)),
+	}
+	for _, f := range diff.Fixed {
+		summary.FixedFindings = append(summary.FixedFindings, baselineEntry(f, sourceDir))
+	}
+	return summary
+}
+
+// WriteBaselineSummary writes the summary to resultsDir and returns the path
+// of the written file
+func WriteBaselineSummary(resultsDir string, summary *BaselineSummary) (string, error) {
+	data, err := json.MarshalIndent(summary, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal baseline summary: %w", err)
+	}
+
+	if err := os.MkdirAll(resultsDir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create results directory: %w", err)
+	}
+
+	path := filepath.Join(resultsDir, BaselineFileName)
+	if err := writeFileAtomic(path, data); err != nil {
+		return "", fmt.Errorf("failed to write baseline summary: %w", err)
+	}
+
+	return path, nil
+}
+
+func baselineEntry(f *pb.Finding, sourceDir string) BaselineEntry {
+	return BaselineEntry{
+		Fingerprint: findings.Fingerprint(f, sourceDir),
+		ScanType:    strings.ToLower(f.ScanType.String()),
+		Severity:    f.Severity.String(),
+		RuleID:      findings.RuleID(f),
+		FilePath:    findings.NormalizePath(f.FilePath, sourceDir),
+		LineNumber:  f.LineNumber,
+	}
+}
@@ -0,0 +1,130 @@
+package report
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	pb "github.com/cloud-scan/cloudscan-orchestrator/generated/proto"
+	"github.com/cloud-scan/cloudscan-runner/internal/findings"
+)
+
+// FindingEntry is the normalized form of a finding printed by local scans
+type FindingEntry struct {
+	Fingerprint string            `json:"fingerprint"`
+	ScanType    string            `json:"scan_type"`
+	Severity    string            `json:"severity"`
+	RuleID      string            `json:"rule_id"`
+	Title       string            `json:"title"`
+	Description string            `json:"description,omitempty"`
+	FilePath    string            `json:"file_path,omitempty"`
+	LineNumber  int32             `json:"line_number,omitempty"`
+	CodeSnippet string            `json:"code_snippet,omitempty"`
+	CveID       string            `json:"cve_id,omitempty"`
+	CweID       string            `json:"cwe_id,omitempty"`
+	References  []string          `json:"references,omitempty"`
+	Details     map[string]string `json:"details,omitempty"`
+}
+
+// NewFindingEntry normalizes a finding; paths are made relative to sourceDir
+// and the details block is split from the description
+func NewFindingEntry(f *pb.Finding, sourceDir string) FindingEntry {
+	description, details := findings.Details(f)
+	if len(details) == 0 {
+		details = nil
+	}
+	return FindingEntry{
+		Fingerprint: findings.Fingerprint(f, sourceDir),
+		ScanType:    strings.ToLower(f.ScanType.String()),
+		Severity:    f.Severity.String(),
+		RuleID:      findings.RuleID(f),
+		Title:       f.Title,
+		Description: description,
+		FilePath:    findings.NormalizePath(f.FilePath, sourceDir),
+		LineNumber:  f.LineNumber,
+		CodeSnippet: f.CodeSnippet,
+		CveID:       f.CveId,
+		CweID:       f.CweId,
+		References:  f.References,
+		Details:     details,
+	}
+}
+
+// WriteFindingsJSON writes findings as a JSON array, most severe first
+func WriteFindingsJSON(w io.Writer, sourceDir string, results []*pb.Finding) error {
+	entries := make([]FindingEntry, 0, len(results))
+	for _, f := range sortBySeverity(results) {
+		entries = append(entries, NewFindingEntry(f, sourceDir))
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(entries); err != nil {
+		return fmt.Errorf("failed to write findings: %w", err)
+	}
+	return nil
+}
+
+// WriteFindingsText writes findings as a table, most severe first, followed
+// by the number of findings per severity
+func WriteFindingsText(w io.Writer, sourceDir string, results []*pb.Finding) error {
+	if len(results) == 0 {
+		_, err := fmt.Fprintln(w, "No findings.")
+		return err
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "SEVERITY\tTYPE\tLOCATION\tTITLE")
+	counts := make(map[pb.Severity]int)
+	for _, f := range sortBySeverity(results) {
+		counts[f.Severity]++
+		location := findings.NormalizePath(f.FilePath, sourceDir)
+		if f.LineNumber > 0 {
+			location = fmt.Sprintf("%s:%d", location, f.LineNumber)
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", f.Severity, strings.ToLower(f.ScanType.String()), location, f.Title)
+	}
+	if err := tw.Flush(); err != nil {
+		return fmt.Errorf("failed to write findings: %w", err)
+	}
+
+	var summary []string
+	for severity := pb.Severity_CRITICAL; severity <= pb.Severity_INFO; severity++ {
+		if counts[severity] > 0 {
+			summary = append(summary, fmt.Sprintf("%d %s", counts[severity], strings.ToLower(severity.String())))
+		}
+	}
+	noun := "findings"
+	if len(results) == 1 {
+		noun = "finding"
+	}
+	_, err := fmt.Fprintf(w, "\n%d %s: %s\n", len(results), noun, strings.Join(summary, ", "))
+	return err
+}
+
+// sortBySeverity returns the findings ordered by severity, file and line
+func sortBySeverity(results []*pb.Finding) []*pb.Finding {
+	sorted := append([]*pb.Finding(nil), results...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		a, b := sorted[i], sorted[j]
+		if rank(a.Severity) != rank(b.Severity) {
+			return rank(a.Severity) < rank(b.Severity)
+		}
+		if a.FilePath != b.FilePath {
+			return a.FilePath < b.FilePath
+		}
+		return a.LineNumber < b.LineNumber
+	})
+	return sorted
+}
+
+// rank orders severities from most to least severe, unspecified last
+func rank(severity pb.Severity) int {
+	if severity == pb.Severity_SEVERITY_UNSPECIFIED {
+		return int(pb.Severity_INFO) + 1
+	}
+	return int(severity)
+}
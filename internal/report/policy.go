@@ -0,0 +1,33 @@
+package report
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/cloud-scan/cloudscan-runner/internal/policy"
+)
+
+// PolicyFileName is the name of the policy verdict written to the results directory
+const PolicyFileName = "policy-verdict.json"
+
+// WritePolicyVerdict writes a policy verdict to resultsDir and returns the
+// path of the written file
+func WritePolicyVerdict(resultsDir string, verdict *policy.Verdict) (string, error) {
+	data, err := json.MarshalIndent(verdict, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal policy verdict: %w", err)
+	}
+
+	if err := os.MkdirAll(resultsDir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create results directory: %w", err)
+	}
+
+	path := filepath.Join(resultsDir, PolicyFileName)
+	if err := writeFileAtomic(path, data); err != nil {
+		return "", fmt.Errorf("failed to write policy verdict: %w", err)
+	}
+
+	return path, nil
+}
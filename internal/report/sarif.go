@@ -0,0 +1,319 @@
+// Package report writes scan results to files in the results directory.
+package report
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	pb "github.com/cloud-scan/cloudscan-orchestrator/generated/proto"
+	"github.com/cloud-scan/cloudscan-runner/internal/findings"
+	"github.com/cloud-scan/cloudscan-runner/internal/sarif"
+	"github.com/cloud-scan/cloudscan-runner/internal/scanners"
+)
+
+// SARIFFileName is the name of the SARIF report written to the results directory
+const SARIFFileName = "results.sarif"
+
+// srcRootID is the uriBaseId that finding paths are relative to
+const srcRootID = "SRCROOT"
+
+// Metadata describes the scanned source for reports
+type Metadata struct {
+	ScanID    string
+	SourceDir string
+	GitURL    string
+	GitBranch string
+	GitCommit string
+}
+
+// WriteSARIF writes a SARIF 2.1.0 log with one run per executed scanner to
+// resultsDir and returns the path of the written file
+func WriteSARIF(resultsDir string, meta Metadata, results []*scanners.Result) (string, error) {
+	doc := BuildSARIF(meta, results)
+
+	data, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal SARIF: %w", err)
+	}
+
+	if err := os.MkdirAll(resultsDir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create results directory: %w", err)
+	}
+
+	path := filepath.Join(resultsDir, SARIFFileName)
+	if err := writeFileAtomic(path, data); err != nil {
+		return "", fmt.Errorf("failed to write SARIF report: %w", err)
+	}
+
+	return path, nil
+}
+
+// BuildSARIF converts scanner results into a SARIF 2.1.0 log
+func BuildSARIF(meta Metadata, results []*scanners.Result) *sarif.Log {
+	doc := &sarif.Log{
+		Version: sarif.Version,
+		Schema:  sarif.Schema,
+		Runs:    make([]*sarif.Run, 0, len(results)),
+	}
+
+	for _, result := range results {
+		if result == nil {
+			continue
+		}
+		doc.Runs = append(doc.Runs, buildRun(meta, result))
+	}
+
+	return doc
+}
+
+// buildRun converts one scanner's result into a SARIF run
+func buildRun(meta Metadata, result *scanners.Result) *sarif.Run {
+	run := &sarif.Run{
+		Tool: sarif.Tool{
+			Driver: sarif.ToolComponent{
+				Name:    result.ScannerName,
+				Version: result.Version,
+			},
+		},
+		Invocations: []*sarif.Invocation{buildInvocation(result)},
+		Results:     make([]*sarif.Result, 0, len(result.Findings)),
+		Properties:  sarif.PropertyBag{},
+	}
+
+	if meta.SourceDir != "" {
+		run.OriginalURIBaseIDs = map[string]*sarif.ArtifactLocation{
+			srcRootID: {URI: dirURI(meta.SourceDir)},
+		}
+	}
+
+	if meta.GitURL != "" {
+		run.VersionControlProvenance = []*sarif.VersionControlDetails{{
+			RepositoryURI: meta.GitURL,
+			RevisionID:    meta.GitCommit,
+			Branch:        meta.GitBranch,
+		}}
+	}
+
+	if result.ScanType != pb.ScanType_SCAN_TYPE_UNSPECIFIED {
+		run.Properties.Set("scanType", strings.ToLower(result.ScanType.String()))
+	}
+	if meta.ScanID != "" {
+		run.Properties.Set("scanId", meta.ScanID)
+	}
+
+	ruleIndex := make(map[string]int)
+	for _, finding := range result.Findings {
+		ruleID := finding.Title
+		idx, ok := ruleIndex[ruleID]
+		if !ok {
+			idx = len(run.Tool.Driver.Rules)
+			ruleIndex[ruleID] = idx
+			run.Tool.Driver.Rules = append(run.Tool.Driver.Rules, buildRule(finding))
+		}
+
+		run.Results = append(run.Results, buildResult(meta, finding, idx))
+	}
+
+	return run
+}
+
+// buildInvocation records whether the scanner ran successfully
+func buildInvocation(result *scanners.Result) *sarif.Invocation {
+	inv := &sarif.Invocation{
+		ExecutionSuccessful: result.Error == nil,
+	}
+	if !result.StartTime.IsZero() {
+		inv.StartTimeUTC = result.StartTime.UTC().Format(time.RFC3339)
+	}
+	if !result.EndTime.IsZero() {
+		inv.EndTimeUTC = result.EndTime.UTC().Format(time.RFC3339)
+	}
+	if result.Skipped != "" {
+		inv.ToolExecutionNotifications = append(inv.ToolExecutionNotifications, &sarif.Notification{
+			Level:   "note",
+			Message: sarif.Message{Text: "Skipped: " + result.Skipped},
+		})
+	}
+	if result.Incomplete != "" {
+		inv.ToolExecutionNotifications = append(inv.ToolExecutionNotifications, &sarif.Notification{
+			Level:   "warning",
+			Message: sarif.Message{Text: "Incomplete: " + result.Incomplete},
+		})
+	}
+	if result.Error != nil {
+		inv.ToolExecutionNotifications = append(inv.ToolExecutionNotifications, &sarif.Notification{
+			Level:   "error",
+			Message: sarif.Message{Text: result.Error.Error()},
+		})
+	}
+	return inv
+}
+
+// buildRule derives rule metadata from the first finding reported for it
+func buildRule(finding *pb.Finding) *sarif.ReportingDescriptor {
+	rule := &sarif.ReportingDescriptor{
+		ID:               finding.Title,
+		ShortDescription: &sarif.MultiformatMessage{Text: finding.Title},
+		DefaultConfiguration: &sarif.ReportingConfiguration{
+			Level: sarifLevel(finding.Severity),
+		},
+		Properties: sarif.PropertyBag{},
+	}
+
+	if len(finding.References) > 0 {
+		rule.HelpURI = finding.References[0]
+	}
+
+	tags := []string{"security", strings.ToLower(finding.ScanType.String())}
+	if finding.CweId != "" {
+		tags = append(tags, finding.CweId)
+	}
+	if finding.CveId != "" {
+		tags = append(tags, finding.CveId)
+	}
+	rule.Properties.Set("tags", tags)
+	rule.Properties.Set("security-severity", securitySeverity(finding.Severity))
+
+	return rule
+}
+
+// buildResult converts a finding into a SARIF result referencing its rule
+func buildResult(meta Metadata, finding *pb.Finding, ruleIdx int) *sarif.Result {
+	message := finding.Description
+	if message == "" {
+		message = finding.Title
+	}
+
+	idx := ruleIdx
+	result := &sarif.Result{
+		RuleID:     finding.Title,
+		RuleIndex:  &idx,
+		Level:      sarifLevel(finding.Severity),
+		Message:    sarif.Message{Text: message},
+		Properties: sarif.PropertyBag{},
+	}
+
+	if finding.FilePath != "" {
+		loc := &sarif.PhysicalLocation{
+			ArtifactLocation: artifactLocation(meta.SourceDir, finding.FilePath),
+		}
+		if finding.LineNumber > 0 {
+			loc.Region = &sarif.Region{
+				StartLine:   int(finding.LineNumber),
+				StartColumn: detailInt(finding, findings.DetailColumn),
+				EndLine:     detailInt(finding, findings.DetailEndLine),
+				EndColumn:   detailInt(finding, findings.DetailEndColumn),
+			}
+			if finding.CodeSnippet != "" {
+				loc.Region.Snippet = &sarif.ArtifactContent{Text: finding.CodeSnippet}
+			}
+		}
+		result.Locations = []*sarif.Location{{PhysicalLocation: loc}}
+	}
+
+	result.Properties.Set("severity", finding.Severity.String())
+	if finding.CweId != "" {
+		result.Properties.Set("cwe", finding.CweId)
+	}
+	if finding.CveId != "" {
+		result.Properties.Set("cve", finding.CveId)
+	}
+	if len(finding.References) > 0 {
+		result.Properties.Set("references", finding.References)
+	}
+
+	return result
+}
+
+// detailInt returns a numeric detail of a finding, or 0
+func detailInt(finding *pb.Finding, key string) int {
+	n, err := strconv.Atoi(findings.Detail(finding, key))
+	if err != nil || n < 0 {
+		return 0
+	}
+	return n
+}
+
+// artifactLocation returns a location relative to SRCROOT for paths inside
+// the source directory and an absolute file URI otherwise
+func artifactLocation(sourceDir, path string) *sarif.ArtifactLocation {
+	if filepath.IsAbs(path) {
+		rel, err := filepath.Rel(sourceDir, path)
+		if sourceDir == "" || err != nil || rel == ".." || strings.HasPrefix(rel, "../") {
+			return &sarif.ArtifactLocation{URI: (&url.URL{Scheme: "file", Path: filepath.ToSlash(path)}).String()}
+		}
+		path = rel
+	}
+
+	loc := &sarif.ArtifactLocation{URI: (&url.URL{Path: filepath.ToSlash(path)}).String()}
+	if sourceDir != "" {
+		loc.URIBaseID = srcRootID
+	}
+	return loc
+}
+
+// dirURI returns the file URI of a directory with a trailing slash
+func dirURI(dir string) string {
+	path := filepath.ToSlash(filepath.Clean(dir))
+	if !strings.HasSuffix(path, "/") {
+		path += "/"
+	}
+	return (&url.URL{Scheme: "file", Path: path}).String()
+}
+
+// sarifLevel maps a severity onto a SARIF result level
+func sarifLevel(severity pb.Severity) string {
+	switch severity {
+	case pb.Severity_CRITICAL, pb.Severity_HIGH:
+		return "error"
+	case pb.Severity_MEDIUM:
+		return "warning"
+	case pb.Severity_LOW, pb.Severity_INFO:
+		return "note"
+	default:
+		return "warning"
+	}
+}
+
+// securitySeverity maps a severity onto the 0-10 score GitHub code scanning expects
+func securitySeverity(severity pb.Severity) string {
+	switch severity {
+	case pb.Severity_CRITICAL:
+		return "9.5"
+	case pb.Severity_HIGH:
+		return "8.0"
+	case pb.Severity_MEDIUM:
+		return "5.5"
+	case pb.Severity_LOW:
+		return "2.0"
+	default:
+		return "0.0"
+	}
+}
+
+// writeFileAtomic writes data to a temporary file and renames it into place
+func writeFileAtomic(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,222 @@
+// Package sarif defines the subset of the SARIF 2.1.0 object model used by
+// the runner to ingest tool output and to export scan results.
+package sarif
+
+import "encoding/json"
+
+// Version is the SARIF specification version
+const Version = "2.1.0"
+
+// Schema is the JSON schema URI of SARIF 2.1.0
+const Schema = "https://json.schemastore.org/sarif-2.1.0.json"
+
+// Log is the top-level SARIF document
+type Log struct {
+	Version string `json:"version"`
+	Schema  string `json:"$schema,omitempty"`
+	Runs    []*Run `json:"runs"`
+}
+
+// Run is the output of a single invocation of a single tool
+type Run struct {
+	Tool                     Tool                         `json:"tool"`
+	Invocations              []*Invocation                `json:"invocations,omitempty"`
+	OriginalURIBaseIDs       map[string]*ArtifactLocation `json:"originalUriBaseIds,omitempty"`
+	Artifacts                []*Artifact                  `json:"artifacts,omitempty"`
+	Results                  []*Result                    `json:"results"`
+	VersionControlProvenance []*VersionControlDetails     `json:"versionControlProvenance,omitempty"`
+	Properties               PropertyBag                  `json:"properties,omitempty"`
+}
+
+// Tool describes the analysis tool that produced a run
+type Tool struct {
+	Driver     ToolComponent    `json:"driver"`
+	Extensions []*ToolComponent `json:"extensions,omitempty"`
+}
+
+// ToolComponent is the tool driver or one of its extensions (plugins, rule packs)
+type ToolComponent struct {
+	Name            string                 `json:"name"`
+	Version         string                 `json:"version,omitempty"`
+	SemanticVersion string                 `json:"semanticVersion,omitempty"`
+	InformationURI  string                 `json:"informationUri,omitempty"`
+	Rules           []*ReportingDescriptor `json:"rules,omitempty"`
+}
+
+// ReportingDescriptor holds rule metadata
+type ReportingDescriptor struct {
+	ID                   string                  `json:"id"`
+	Name                 string                  `json:"name,omitempty"`
+	ShortDescription     *MultiformatMessage     `json:"shortDescription,omitempty"`
+	FullDescription      *MultiformatMessage     `json:"fullDescription,omitempty"`
+	Help                 *MultiformatMessage     `json:"help,omitempty"`
+	HelpURI              string                  `json:"helpUri,omitempty"`
+	DefaultConfiguration *ReportingConfiguration `json:"defaultConfiguration,omitempty"`
+	Properties           PropertyBag             `json:"properties,omitempty"`
+}
+
+// ReportingConfiguration holds the default level of a rule
+type ReportingConfiguration struct {
+	Level string `json:"level,omitempty"`
+}
+
+// MultiformatMessage is a message with plain text and optional markdown
+type MultiformatMessage struct {
+	Text     string `json:"text,omitempty"`
+	Markdown string `json:"markdown,omitempty"`
+}
+
+// Message is a result or notification message
+type Message struct {
+	Text     string `json:"text,omitempty"`
+	Markdown string `json:"markdown,omitempty"`
+	ID       string `json:"id,omitempty"`
+}
+
+// Invocation describes how a tool run was executed
+type Invocation struct {
+	ExecutionSuccessful        bool            `json:"executionSuccessful"`
+	StartTimeUTC               string          `json:"startTimeUtc,omitempty"`
+	EndTimeUTC                 string          `json:"endTimeUtc,omitempty"`
+	ExitCode                   *int            `json:"exitCode,omitempty"`
+	ToolExecutionNotifications []*Notification `json:"toolExecutionNotifications,omitempty"`
+}
+
+// Notification is a message emitted by the tool itself
+type Notification struct {
+	Level   string  `json:"level,omitempty"`
+	Message Message `json:"message"`
+}
+
+// Artifact describes a file referenced by results
+type Artifact struct {
+	Location *ArtifactLocation `json:"location,omitempty"`
+}
+
+// ArtifactLocation identifies a file by URI, optionally relative to a base ID
+type ArtifactLocation struct {
+	URI       string `json:"uri,omitempty"`
+	URIBaseID string `json:"uriBaseId,omitempty"`
+	Index     *int   `json:"index,omitempty"`
+}
+
+// Result is a single finding reported by a tool
+type Result struct {
+	RuleID              string                        `json:"ruleId,omitempty"`
+	RuleIndex           *int                          `json:"ruleIndex,omitempty"`
+	Rule                *ReportingDescriptorReference `json:"rule,omitempty"`
+	Level               string                        `json:"level,omitempty"`
+	Message             Message                       `json:"message"`
+	Locations           []*Location                   `json:"locations,omitempty"`
+	CodeFlows           []*CodeFlow                   `json:"codeFlows,omitempty"`
+	PartialFingerprints map[string]string             `json:"partialFingerprints,omitempty"`
+	Properties          PropertyBag                   `json:"properties,omitempty"`
+}
+
+// ReportingDescriptorReference points at a rule by ID or index
+type ReportingDescriptorReference struct {
+	ID            string                  `json:"id,omitempty"`
+	Index         *int                    `json:"index,omitempty"`
+	ToolComponent *ToolComponentReference `json:"toolComponent,omitempty"`
+}
+
+// ToolComponentReference points at the driver or an extension
+type ToolComponentReference struct {
+	Name  string `json:"name,omitempty"`
+	Index *int   `json:"index,omitempty"`
+}
+
+// Location is where a result was detected
+type Location struct {
+	PhysicalLocation *PhysicalLocation `json:"physicalLocation,omitempty"`
+	Message          *Message          `json:"message,omitempty"`
+}
+
+// PhysicalLocation is a file and region
+type PhysicalLocation struct {
+	ArtifactLocation *ArtifactLocation `json:"artifactLocation,omitempty"`
+	Region           *Region           `json:"region,omitempty"`
+	ContextRegion    *Region           `json:"contextRegion,omitempty"`
+}
+
+// Region is a span of lines and columns within a file
+type Region struct {
+	StartLine   int              `json:"startLine,omitempty"`
+	StartColumn int              `json:"startColumn,omitempty"`
+	EndLine     int              `json:"endLine,omitempty"`
+	EndColumn   int              `json:"endColumn,omitempty"`
+	Snippet     *ArtifactContent `json:"snippet,omitempty"`
+}
+
+// ArtifactContent holds a snippet of file content
+type ArtifactContent struct {
+	Text string `json:"text,omitempty"`
+}
+
+// CodeFlow is a sequence of locations describing how data reached the result
+type CodeFlow struct {
+	Message     *Message      `json:"message,omitempty"`
+	ThreadFlows []*ThreadFlow `json:"threadFlows"`
+}
+
+// ThreadFlow is the ordered list of steps in a code flow
+type ThreadFlow struct {
+	Locations []*ThreadFlowLocation `json:"locations"`
+}
+
+// ThreadFlowLocation is a single step of a thread flow
+type ThreadFlowLocation struct {
+	Location *Location `json:"location,omitempty"`
+}
+
+// VersionControlDetails records the revision that was analyzed
+type VersionControlDetails struct {
+	RepositoryURI string `json:"repositoryUri"`
+	RevisionID    string `json:"revisionId,omitempty"`
+	Branch        string `json:"branch,omitempty"`
+}
+
+// PropertyBag holds tool-specific properties such as "tags" and "security-severity"
+type PropertyBag map[string]json.RawMessage
+
+// String returns a string property, accepting JSON strings and numbers
+func (p PropertyBag) String(key string) string {
+	raw, ok := p[key]
+	if !ok {
+		return ""
+	}
+
+	var s string
+	if err := json.Unmarshal(raw, &s); err == nil {
+		return s
+	}
+
+	var n json.Number
+	if err := json.Unmarshal(raw, &n); err == nil {
+		return n.String()
+	}
+	return ""
+}
+
+// Strings returns a string list property such as "tags"
+func (p PropertyBag) Strings(key string) []string {
+	raw, ok := p[key]
+	if !ok {
+		return nil
+	}
+
+	var list []string
+	if err := json.Unmarshal(raw, &list); err != nil {
+		return nil
+	}
+	return list
+}
+
+// Set stores a property value
+func (p PropertyBag) Set(key string, value interface{}) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return
+	}
+	p[key] = data
+}
@@ -0,0 +1,160 @@
+package sbom
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// CycloneDX JSON document (subset of the 1.5 specification)
+type cdxDocument struct {
+	BOMFormat    string         `json:"bomFormat"`
+	SpecVersion  string         `json:"specVersion"`
+	SerialNumber string         `json:"serialNumber"`
+	Version      int            `json:"version"`
+	Metadata     cdxMetadata    `json:"metadata"`
+	Components   []cdxComponent `json:"components"`
+}
+
+type cdxMetadata struct {
+	Timestamp  string        `json:"timestamp"`
+	Tools      cdxTools      `json:"tools"`
+	Component  *cdxComponent `json:"component,omitempty"`
+	Properties []cdxProperty `json:"properties,omitempty"`
+}
+
+type cdxTools struct {
+	Components []cdxComponent `json:"components"`
+}
+
+type cdxComponent struct {
+	Type       string        `json:"type"`
+	BOMRef     string        `json:"bom-ref,omitempty"`
+	Name       string        `json:"name"`
+	Version    string        `json:"version,omitempty"`
+	PURL       string        `json:"purl,omitempty"`
+	Licenses   []cdxLicense  `json:"licenses,omitempty"`
+	Properties []cdxProperty `json:"properties,omitempty"`
+}
+
+type cdxLicense struct {
+	License    *cdxLicenseName `json:"license,omitempty"`
+	Expression string          `json:"expression,omitempty"`
+}
+
+type cdxLicenseName struct {
+	Name string `json:"name"`
+}
+
+type cdxProperty struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// CycloneDX renders the inventory as a CycloneDX 1.5 JSON document
+func CycloneDX(inv *Inventory, meta Metadata) ([]byte, error) {
+	doc := cdxDocument{
+		BOMFormat:    "CycloneDX",
+		SpecVersion:  "1.5",
+		SerialNumber: "urn:uuid:" + uuid.New().String(),
+		Version:      1,
+		Metadata: cdxMetadata{
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+			Tools: cdxTools{Components: []cdxComponent{{
+				Type:    "application",
+				Name:    "cloudscan-runner",
+				Version: meta.ToolVersion,
+			}}},
+			Component:  rootComponent(meta),
+			Properties: metadataProperties(meta),
+		},
+		Components: make([]cdxComponent, 0, len(inv.Components)),
+	}
+
+	refs := make(map[string]int)
+	for _, c := range inv.Components {
+		component := cdxComponent{
+			Type:    "library",
+			BOMRef:  uniqueRef(refs, componentRef(c)),
+			Name:    c.Name,
+			Version: c.Version,
+			PURL:    c.PURL,
+		}
+		for _, license := range c.Licenses {
+			component.Licenses = append(component.Licenses, cdxLicenseEntry(license))
+		}
+		if c.Type != "" {
+			component.Properties = append(component.Properties, cdxProperty{Name: "cloudscan:package_type", Value: c.Type})
+		}
+		if c.Source != "" {
+			component.Properties = append(component.Properties, cdxProperty{Name: "cloudscan:source_file", Value: c.Source})
+		}
+		doc.Components = append(doc.Components, component)
+	}
+
+	data, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal CycloneDX document: %w", err)
+	}
+	return data, nil
+}
+
+// rootComponent describes the scanned repository itself
+func rootComponent(meta Metadata) *cdxComponent {
+	name := meta.GitURL
+	if name == "" {
+		name = meta.ProjectID
+	}
+	if name == "" {
+		return nil
+	}
+	return &cdxComponent{
+		Type:    "application",
+		BOMRef:  "root",
+		Name:    name,
+		Version: meta.GitCommit,
+	}
+}
+
+// metadataProperties records the scan identity as CycloneDX properties
+func metadataProperties(meta Metadata) []cdxProperty {
+	var props []cdxProperty
+	add := func(name, value string) {
+		if value != "" {
+			props = append(props, cdxProperty{Name: name, Value: value})
+		}
+	}
+	add("cloudscan:scan_id", meta.ScanID)
+	add("cloudscan:project_id", meta.ProjectID)
+	add("cloudscan:git_url", meta.GitURL)
+	add("cloudscan:git_commit", meta.GitCommit)
+	return props
+}
+
+// cdxLicenseEntry uses an expression for compound licenses and a name otherwise
+func cdxLicenseEntry(license string) cdxLicense {
+	if isLicenseExpression(license) && strings.ContainsAny(license, " ") {
+		return cdxLicense{Expression: license}
+	}
+	return cdxLicense{License: &cdxLicenseName{Name: license}}
+}
+
+// componentRef returns a stable reference for a component
+func componentRef(c Component) string {
+	if c.PURL != "" {
+		return c.PURL
+	}
+	return c.Name + "@" + c.Version
+}
+
+// uniqueRef disambiguates references that occur more than once
+func uniqueRef(seen map[string]int, ref string) string {
+	seen[ref]++
+	if n := seen[ref]; n > 1 {
+		return fmt.Sprintf("%s#%d", ref, n)
+	}
+	return ref
+}
@@ -0,0 +1,51 @@
+package sbom
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// DetectFormat identifies the format of a supplied SBOM document. CycloneDX
+// JSON, SPDX JSON and SPDX tag-value documents are recognized.
+func DetectFormat(data []byte) (Format, error) {
+	trimmed := bytes.TrimSpace(bytes.TrimPrefix(data, []byte("\xef\xbb\xbf")))
+	if len(trimmed) == 0 {
+		return "", fmt.Errorf("SBOM document is empty")
+	}
+
+	switch trimmed[0] {
+	case '{':
+		var doc struct {
+			BOMFormat   string `json:"bomFormat"`
+			SPDXVersion string `json:"spdxVersion"`
+		}
+		if err := json.Unmarshal(trimmed, &doc); err != nil {
+			return "", fmt.Errorf("failed to parse SBOM JSON: %w", err)
+		}
+		switch {
+		case doc.BOMFormat == "CycloneDX":
+			return FormatCycloneDX, nil
+		case doc.SPDXVersion != "":
+			return FormatSPDX, nil
+		}
+		return "", fmt.Errorf("JSON document is neither CycloneDX nor SPDX")
+	case '<':
+		return "", fmt.Errorf("XML SBOMs are not supported, supply CycloneDX JSON instead")
+	}
+
+	if bytes.HasPrefix(trimmed, []byte("SPDXVersion:")) {
+		return FormatSPDX, nil
+	}
+	return "", fmt.Errorf("unrecognized SBOM document format")
+}
+
+// DetectFileFormat identifies the format of an SBOM document on disk
+func DetectFileFormat(path string) (Format, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read SBOM: %w", err)
+	}
+	return DetectFormat(data)
+}
@@ -0,0 +1,163 @@
+// Package sbom builds CycloneDX and SPDX software bills of materials for
+// the scanned workspace.
+package sbom
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"sort"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Component is a package found in the workspace
+type Component struct {
+	Name     string
+	Version  string
+	PURL     string
+	Type     string   // Package ecosystem (e.g. gomod, npm, pip)
+	Licenses []string // Declared license names or SPDX expressions
+	Source   string   // Manifest or lockfile the package was found in
+}
+
+// Inventory is the package inventory of a workspace
+type Inventory struct {
+	Components []Component
+}
+
+// Metadata identifies the scan an SBOM was produced for
+type Metadata struct {
+	ScanID      string
+	ProjectID   string
+	GitURL      string
+	GitCommit   string
+	ToolVersion string // Runner version recorded as the SBOM creator
+}
+
+// Collector gathers the package inventory of a workspace using Trivy
+type Collector struct {
+	logger *log.Entry
+}
+
+// NewCollector creates a new inventory collector
+func NewCollector() *Collector {
+	return &Collector{
+		logger: log.WithField("component", "sbom"),
+	}
+}
+
+// IsAvailable checks if trivy is installed
+func (c *Collector) IsAvailable() bool {
+	_, err := exec.LookPath("trivy")
+	return err == nil
+}
+
+// Collect lists every package Trivy detects in sourceDir
+func (c *Collector) Collect(ctx context.Context, sourceDir string) (*Inventory, error) {
+	c.logger.WithField("source_dir", sourceDir).Info("Collecting package inventory")
+
+	if !c.IsAvailable() {
+		return nil, fmt.Errorf("trivy is not installed")
+	}
+
+	f, err := os.CreateTemp("", "trivy-inventory-*.json")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create results file: %w", err)
+	}
+	resultsFile := f.Name()
+	f.Close()
+	defer os.Remove(resultsFile)
+
+	// The license scanner needs no vulnerability DB and fills package licenses
+	cmd := exec.CommandContext(ctx, "trivy",
+		"fs",
+		"--format=json",
+		"--output="+resultsFile,
+		"--list-all-pkgs",
+		"--scanners=license",
+		sourceDir,
+	)
+
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		c.logger.WithError(err).WithField("output", string(output)).Warn("Trivy exited with error while listing packages")
+	}
+
+	inv, err := parseInventory(resultsFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse trivy inventory: %w", err)
+	}
+
+	c.logger.WithField("components", len(inv.Components)).Info("Package inventory collected")
+	return inv, nil
+}
+
+// parseInventory parses the Packages lists of Trivy JSON output
+func parseInventory(resultsFile string) (*Inventory, error) {
+	data, err := os.ReadFile(resultsFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read results: %w", err)
+	}
+
+	var result struct {
+		Results []struct {
+			Target   string `json:"Target"`
+			Type     string `json:"Type"`
+			Packages []struct {
+				Name       string `json:"Name"`
+				Version    string `json:"Version"`
+				Identifier struct {
+					PURL string `json:"PURL"`
+				} `json:"Identifier"`
+				Licenses []string `json:"Licenses"`
+				FilePath string   `json:"FilePath"`
+			} `json:"Packages"`
+		} `json:"Results"`
+	}
+
+	if err := json.Unmarshal(data, &result); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal JSON: %w", err)
+	}
+
+	inv := &Inventory{}
+	seen := make(map[string]bool)
+	for _, r := range result.Results {
+		for _, p := range r.Packages {
+			source := p.FilePath
+			if source == "" {
+				source = r.Target
+			}
+
+			key := p.Identifier.PURL + "|" + p.Name + "|" + p.Version + "|" + source
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+
+			inv.Components = append(inv.Components, Component{
+				Name:     p.Name,
+				Version:  p.Version,
+				PURL:     p.Identifier.PURL,
+				Type:     r.Type,
+				Licenses: p.Licenses,
+				Source:   source,
+			})
+		}
+	}
+
+	sort.SliceStable(inv.Components, func(i, j int) bool {
+		a, b := inv.Components[i], inv.Components[j]
+		if a.Name != b.Name {
+			return a.Name < b.Name
+		}
+		if a.Version != b.Version {
+			return a.Version < b.Version
+		}
+		return a.Source < b.Source
+	})
+
+	return inv, nil
+}
@@ -0,0 +1,90 @@
+package sbom
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// Format identifies an SBOM document format
+type Format string
+
+const (
+	// FormatCycloneDX is CycloneDX 1.5 JSON
+	FormatCycloneDX Format = "cyclonedx"
+	// FormatSPDX is SPDX 2.3 JSON
+	FormatSPDX Format = "spdx"
+)
+
+// ParseFormat parses an SBOM format name
+func ParseFormat(name string) (Format, error) {
+	switch Format(strings.ToLower(strings.TrimSpace(name))) {
+	case FormatCycloneDX, "cdx":
+		return FormatCycloneDX, nil
+	case FormatSPDX:
+		return FormatSPDX, nil
+	default:
+		return "", fmt.Errorf("unknown SBOM format %q", name)
+	}
+}
+
+// FileName returns the file name used for the format in the results directory
+func (f Format) FileName() string {
+	switch f {
+	case FormatCycloneDX:
+		return "sbom.cdx.json"
+	case FormatSPDX:
+		return "sbom.spdx.json"
+	default:
+		return "sbom.json"
+	}
+}
+
+// ContentType returns the media type of the format
+func (f Format) ContentType() string {
+	switch f {
+	case FormatCycloneDX:
+		return "application/vnd.cyclonedx+json"
+	case FormatSPDX:
+		return "application/spdx+json"
+	default:
+		return "application/json"
+	}
+}
+
+// Render renders the inventory in the given format
+func Render(inv *Inventory, meta Metadata, format Format) ([]byte, error) {
+	switch format {
+	case FormatCycloneDX:
+		return CycloneDX(inv, meta)
+	case FormatSPDX:
+		return SPDX(inv, meta)
+	default:
+		return nil, fmt.Errorf("unknown SBOM format %q", format)
+	}
+}
+
+// Upload PUTs a document to a presigned URL
+func Upload(ctx context.Context, client *http.Client, presignedURL string, data []byte, contentType string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, presignedURL, bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.ContentLength = int64(len(data))
+	req.Header.Set("Content-Type", contentType)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to upload: %w", err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("upload failed with status: %s", resp.Status)
+	}
+	return nil
+}
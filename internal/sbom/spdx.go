@@ -0,0 +1,224 @@
+package sbom
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// spdxNoAssertion marks a field the generator makes no claim about
+const spdxNoAssertion = "NOASSERTION"
+
+var (
+	licenseIDPattern  = regexp.MustCompile(`^[A-Za-z0-9.+-]+$`)
+	spdxIDUnsafeChars = regexp.MustCompile(`[^A-Za-z0-9.-]+`)
+)
+
+// SPDX JSON document (subset of the 2.3 specification)
+type spdxDocument struct {
+	SPDXVersion       string             `json:"spdxVersion"`
+	DataLicense       string             `json:"dataLicense"`
+	SPDXID            string             `json:"SPDXID"`
+	Name              string             `json:"name"`
+	DocumentNamespace string             `json:"documentNamespace"`
+	CreationInfo      spdxCreationInfo   `json:"creationInfo"`
+	Packages          []spdxPackage      `json:"packages"`
+	Relationships     []spdxRelationship `json:"relationships"`
+}
+
+type spdxCreationInfo struct {
+	Created  string   `json:"created"`
+	Creators []string `json:"creators"`
+	Comment  string   `json:"comment,omitempty"`
+}
+
+type spdxPackage struct {
+	SPDXID           string            `json:"SPDXID"`
+	Name             string            `json:"name"`
+	VersionInfo      string            `json:"versionInfo,omitempty"`
+	DownloadLocation string            `json:"downloadLocation"`
+	FilesAnalyzed    bool              `json:"filesAnalyzed"`
+	LicenseConcluded string            `json:"licenseConcluded"`
+	LicenseDeclared  string            `json:"licenseDeclared"`
+	CopyrightText    string            `json:"copyrightText"`
+	SourceInfo       string            `json:"sourceInfo,omitempty"`
+	Comment          string            `json:"comment,omitempty"`
+	ExternalRefs     []spdxExternalRef `json:"externalRefs,omitempty"`
+}
+
+type spdxExternalRef struct {
+	ReferenceCategory string `json:"referenceCategory"`
+	ReferenceType     string `json:"referenceType"`
+	ReferenceLocator  string `json:"referenceLocator"`
+}
+
+type spdxRelationship struct {
+	SPDXElementID      string `json:"spdxElementId"`
+	RelationshipType   string `json:"relationshipType"`
+	RelatedSPDXElement string `json:"relatedSpdxElement"`
+}
+
+// SPDX renders the inventory as an SPDX 2.3 JSON document
+func SPDX(inv *Inventory, meta Metadata) ([]byte, error) {
+	name := meta.GitURL
+	if name == "" {
+		name = "project-" + meta.ProjectID
+	}
+
+	creator := "Tool: cloudscan-runner"
+	if meta.ToolVersion != "" {
+		creator += "-" + meta.ToolVersion
+	}
+
+	doc := spdxDocument{
+		SPDXVersion:       "SPDX-2.3",
+		DataLicense:       "CC0-1.0",
+		SPDXID:            "SPDXRef-DOCUMENT",
+		Name:              name,
+		DocumentNamespace: fmt.Sprintf("https://cloudscan.io/spdx/%s/%s", meta.ScanID, uuid.New().String()),
+		CreationInfo: spdxCreationInfo{
+			Created:  time.Now().UTC().Format(time.RFC3339),
+			Creators: []string{creator},
+			Comment:  metadataComment(meta),
+		},
+		Packages: make([]spdxPackage, 0, len(inv.Components)+1),
+	}
+
+	root := spdxPackage{
+		SPDXID:           "SPDXRef-Root",
+		Name:             name,
+		VersionInfo:      meta.GitCommit,
+		DownloadLocation: rootDownloadLocation(meta),
+		LicenseConcluded: spdxNoAssertion,
+		LicenseDeclared:  spdxNoAssertion,
+		CopyrightText:    spdxNoAssertion,
+	}
+	doc.Packages = append(doc.Packages, root)
+	doc.Relationships = append(doc.Relationships, spdxRelationship{
+		SPDXElementID:      doc.SPDXID,
+		RelationshipType:   "DESCRIBES",
+		RelatedSPDXElement: root.SPDXID,
+	})
+
+	ids := make(map[string]int)
+	for _, c := range inv.Components {
+		pkg := spdxPackage{
+			SPDXID:           uniqueSPDXID(ids, "SPDXRef-Package-"+spdxIDUnsafeChars.ReplaceAllString(c.Name+"-"+c.Version, "-")),
+			Name:             c.Name,
+			VersionInfo:      c.Version,
+			DownloadLocation: spdxNoAssertion,
+			LicenseConcluded: spdxNoAssertion,
+			LicenseDeclared:  spdxNoAssertion,
+			CopyrightText:    spdxNoAssertion,
+		}
+		if expr := spdxLicenseExpression(c.Licenses); expr != "" {
+			pkg.LicenseDeclared = expr
+		} else if len(c.Licenses) > 0 {
+			pkg.Comment = "Declared licenses: " + strings.Join(c.Licenses, ", ")
+		}
+		if c.Source != "" {
+			pkg.SourceInfo = "Found in " + c.Source
+		}
+		if c.PURL != "" {
+			pkg.ExternalRefs = []spdxExternalRef{{
+				ReferenceCategory: "PACKAGE-MANAGER",
+				ReferenceType:     "purl",
+				ReferenceLocator:  c.PURL,
+			}}
+		}
+
+		doc.Packages = append(doc.Packages, pkg)
+		doc.Relationships = append(doc.Relationships, spdxRelationship{
+			SPDXElementID:      root.SPDXID,
+			RelationshipType:   "DEPENDS_ON",
+			RelatedSPDXElement: pkg.SPDXID,
+		})
+	}
+
+	data, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal SPDX document: %w", err)
+	}
+	return data, nil
+}
+
+// uniqueSPDXID disambiguates SPDX IDs that occur more than once
+func uniqueSPDXID(seen map[string]int, id string) string {
+	seen[id]++
+	if n := seen[id]; n > 1 {
+		return fmt.Sprintf("%s-%d", id, n)
+	}
+	return id
+}
+
+// metadataComment records the scan identity in the creation info comment
+func metadataComment(meta Metadata) string {
+	var lines []string
+	add := func(name, value string) {
+		if value != "" {
+			lines = append(lines, name+": "+value)
+		}
+	}
+	add("cloudscan:scan_id", meta.ScanID)
+	add("cloudscan:project_id", meta.ProjectID)
+	add("cloudscan:git_url", meta.GitURL)
+	add("cloudscan:git_commit", meta.GitCommit)
+	return strings.Join(lines, "\n")
+}
+
+// rootDownloadLocation returns the VCS location of the scanned commit
+func rootDownloadLocation(meta Metadata) string {
+	if meta.GitURL == "" {
+		return spdxNoAssertion
+	}
+	location := "git+" + meta.GitURL
+	if meta.GitCommit != "" {
+		location += "@" + meta.GitCommit
+	}
+	return location
+}
+
+// spdxLicenseExpression combines declared licenses into an SPDX expression,
+// or returns "" when any of them is not expressible
+func spdxLicenseExpression(licenses []string) string {
+	if len(licenses) == 0 {
+		return ""
+	}
+
+	parts := make([]string, 0, len(licenses))
+	for _, license := range licenses {
+		if !isLicenseExpression(license) {
+			return ""
+		}
+		if strings.Contains(license, " ") {
+			license = "(" + license + ")"
+		}
+		parts = append(parts, license)
+	}
+	return strings.Join(parts, " AND ")
+}
+
+// isLicenseExpression reports whether s looks like an SPDX license ID or a
+// simple AND/OR/WITH expression of IDs
+func isLicenseExpression(s string) bool {
+	tokens := strings.Fields(s)
+	if len(tokens) == 0 || len(tokens)%2 == 0 {
+		return false
+	}
+	for i, token := range tokens {
+		if i%2 == 1 {
+			if token != "AND" && token != "OR" && token != "WITH" {
+				return false
+			}
+			continue
+		}
+		if !licenseIDPattern.MatchString(token) {
+			return false
+		}
+	}
+	return true
+}
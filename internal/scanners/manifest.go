@@ -0,0 +1,641 @@
+package scanners
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	pb "github.com/cloud-scan/cloudscan-orchestrator/generated/proto"
+	log "github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v3"
+)
+
+// Output formats supported by manifest-driven scanners
+const (
+	FormatJSON  = "json"
+	FormatJSONL = "jsonl"
+	FormatSARIF = "sarif"
+)
+
+// Argument placeholders substituted when a manifest scanner runs
+const (
+	placeholderSourceDir  = "{{sourceDir}}"
+	placeholderOutputFile = "{{outputFile}}"
+)
+
+// Manifest declares an external tool that can be run as a scanner
+// without writing a Go adapter. Manifests are YAML or JSON files.
+type Manifest struct {
+	// Name uniquely identifies the scanner
+	Name string `yaml:"name"`
+
+	// ScanType is the scan type served (e.g. "sast"); every finding is
+	// reported with it, so tools covering several types need a manifest
+	// per type
+	ScanType string `yaml:"scan_type"`
+
+	// Priority orders this scanner against others of the same type
+	Priority int `yaml:"priority"`
+
+	// Source is what the tool analyzes: "code" (default) or "sbom". For SBOM
+	// scanners {{sourceDir}} is the path of the supplied SBOM document.
+	Source string `yaml:"source"`
+
+	// Binary is the executable looked up in PATH
+	Binary string `yaml:"binary"`
+
+	// Args is the argument template; {{sourceDir}} and {{outputFile}} are substituted.
+	// Results are read from {{outputFile}} when referenced, otherwise from stdout.
+	Args []string `yaml:"args"`
+
+	// SuccessExitCodes lists the exit codes of a successful run (e.g. [0, 1]
+	// for tools exiting 1 on findings). When empty, any exit code is accepted
+	// as long as the tool produced output.
+	SuccessExitCodes []int `yaml:"success_exit_codes"`
+
+	// VersionArgs are passed to the binary to print its version (e.g. ["--version"])
+	VersionArgs []string `yaml:"version_args"`
+
+	// MinVersion is the oldest supported tool version (requires version_args)
+	MinVersion string `yaml:"min_version"`
+
+	// DataPaths lists files or directories the tool needs, such as rule packs
+	DataPaths []string `yaml:"data_paths"`
+
+	// Output describes how to read the tool's results
+	Output ManifestOutput `yaml:"output"`
+
+	// Fields maps result fields onto pb.Finding (ignored for SARIF output)
+	Fields ManifestFields `yaml:"fields"`
+
+	// SeverityMap maps tool severity values onto CRITICAL, HIGH, MEDIUM, LOW or INFO
+	// (ignored for SARIF output, which carries its own levels)
+	SeverityMap map[string]string `yaml:"severity_map"`
+
+	// DefaultSeverity applies when the tool severity is missing or unmapped
+	DefaultSeverity string `yaml:"default_severity"`
+
+	scanType        pb.ScanType
+	source          SourceKind
+	severityMap     map[string]pb.Severity
+	defaultSeverity pb.Severity
+}
+
+// ManifestOutput describes the tool output
+type ManifestOutput struct {
+	// Format is json, jsonl or sarif
+	Format string `yaml:"format"`
+
+	// Results is the dotted path to the results array in JSON output
+	// (empty means the document itself is the array)
+	Results string `yaml:"results"`
+}
+
+// ManifestFields maps result fields onto pb.Finding. Each value is either a
+// dotted path into a result object (e.g. "extra.metadata.cwe.0") or a
+// template combining paths (e.g. "{{rule_id}}: {{message}}").
+type ManifestFields struct {
+	Title       string `yaml:"title"`
+	Description string `yaml:"description"`
+	FilePath    string `yaml:"file_path"`
+	LineNumber  string `yaml:"line_number"`
+	CodeSnippet string `yaml:"code_snippet"`
+	CveID       string `yaml:"cve_id"`
+	CweID       string `yaml:"cwe_id"`
+	References  string `yaml:"references"`
+	Severity    string `yaml:"severity"`
+}
+
+var fieldTemplatePattern = regexp.MustCompile(`\{\{\s*([^}\s]+)\s*\}\}`)
+
+// ParseManifest parses and validates a scanner manifest
+func ParseManifest(data []byte) (*Manifest, error) {
+	var m Manifest
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&m); err != nil {
+		return nil, fmt.Errorf("failed to parse manifest: %w", err)
+	}
+
+	if err := m.validate(); err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
+// validate checks required fields and resolves enum values
+func (m *Manifest) validate() error {
+	if m.Name == "" {
+		return fmt.Errorf("manifest is missing name")
+	}
+	if m.Binary == "" {
+		return fmt.Errorf("manifest %q is missing binary", m.Name)
+	}
+	if m.ScanType == "" {
+		return fmt.Errorf("manifest %q is missing scan_type", m.Name)
+	}
+
+	scanType, ok := ParseScanType(m.ScanType)
+	if !ok {
+		return fmt.Errorf("manifest %q has unknown scan type %q", m.Name, m.ScanType)
+	}
+	m.scanType = scanType
+
+	if m.MinVersion != "" {
+		if len(m.VersionArgs) == 0 {
+			return fmt.Errorf("manifest %q sets min_version without version_args", m.Name)
+		}
+		if ExtractVersion(m.MinVersion) == "" {
+			return fmt.Errorf("manifest %q has invalid min_version %q", m.Name, m.MinVersion)
+		}
+	}
+
+	source, err := ParseSourceKind(m.Source)
+	if err != nil {
+		return fmt.Errorf("manifest %q: %w", m.Name, err)
+	}
+	m.source = source
+
+	m.Output.Format = strings.ToLower(m.Output.Format)
+	switch m.Output.Format {
+	case "":
+		m.Output.Format = FormatJSON
+	case FormatJSON, FormatJSONL, FormatSARIF:
+	default:
+		return fmt.Errorf("manifest %q has unknown output format %q", m.Name, m.Output.Format)
+	}
+
+	// SARIF output is self-describing; other formats need at least a title mapping
+	if m.Output.Format != FormatSARIF && m.Fields.Title == "" {
+		return fmt.Errorf("manifest %q must map fields.title", m.Name)
+	}
+
+	m.severityMap = make(map[string]pb.Severity, len(m.SeverityMap))
+	for from, to := range m.SeverityMap {
+		severity, ok := parseSeverity(to)
+		if !ok {
+			return fmt.Errorf("manifest %q maps %q to unknown severity %q", m.Name, from, to)
+		}
+		m.severityMap[strings.ToUpper(from)] = severity
+	}
+
+	m.defaultSeverity = pb.Severity_MEDIUM
+	if m.DefaultSeverity != "" {
+		severity, ok := parseSeverity(m.DefaultSeverity)
+		if !ok {
+			return fmt.Errorf("manifest %q has unknown default_severity %q", m.Name, m.DefaultSeverity)
+		}
+		m.defaultSeverity = severity
+	}
+
+	return nil
+}
+
+// parseSeverity converts a severity name such as "high" to its proto value
+func parseSeverity(name string) (pb.Severity, bool) {
+	value, ok := pb.Severity_value[strings.ToUpper(strings.TrimSpace(name))]
+	if !ok || pb.Severity(value) == pb.Severity_SEVERITY_UNSPECIFIED {
+		return pb.Severity_SEVERITY_UNSPECIFIED, false
+	}
+	return pb.Severity(value), true
+}
+
+// LoadManifests registers a scanner for every manifest file (*.yaml, *.yml,
+// *.json) in dir. A missing directory is not an error. Invalid manifests are
+// skipped and reported in the returned error.
+func LoadManifests(dir string) (int, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return 0, nil
+		}
+		return 0, fmt.Errorf("failed to read manifest directory: %w", err)
+	}
+
+	var errs []error
+	loaded := 0
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		switch strings.ToLower(filepath.Ext(entry.Name())) {
+		case ".yaml", ".yml", ".json":
+		default:
+			continue
+		}
+
+		path := filepath.Join(dir, entry.Name())
+		data, err := os.ReadFile(path)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", path, err))
+			continue
+		}
+
+		m, err := ParseManifest(data)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", path, err))
+			continue
+		}
+
+		if err := Register(Registration{
+			Name:       m.Name,
+			ScanTypes:  []pb.ScanType{m.scanType},
+			Priority:   m.Priority,
+			Source:     m.source,
+			MinVersion: m.MinVersion,
+			New:        m.newScanner,
+		}); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", path, err))
+			continue
+		}
+
+		log.WithFields(log.Fields{
+			"scanner":  m.Name,
+			"manifest": path,
+		}).Info("Registered manifest scanner")
+		loaded++
+	}
+
+	return loaded, errors.Join(errs...)
+}
+
+// newScanner constructs the scanner for the manifest's output format
+func (m *Manifest) newScanner() Scanner {
+	if m.Output.Format == FormatSARIF {
+		scanner := NewSARIFScanner(m.Name, m.scanType, m.Binary, m.Args)
+		scanner.versionArgs = m.VersionArgs
+		scanner.successExitCodes = m.SuccessExitCodes
+		scanner.dataPaths = m.DataPaths
+		return scanner
+	}
+	return NewManifestScanner(m)
+}
+
+// ManifestScanner runs an external tool described by a Manifest
+type ManifestScanner struct {
+	manifest *Manifest
+	logger   *log.Entry
+}
+
+// NewManifestScanner creates a scanner from a validated manifest
+func NewManifestScanner(m *Manifest) *ManifestScanner {
+	return &ManifestScanner{
+		manifest: m,
+		logger:   log.WithField("scanner", m.Name),
+	}
+}
+
+// Name returns the scanner name
+func (s *ManifestScanner) Name() string {
+	return s.manifest.Name
+}
+
+// ScanType returns the scan type
+func (s *ManifestScanner) ScanType() pb.ScanType {
+	return s.manifest.scanType
+}
+
+// IsAvailable checks if the tool binary is installed
+func (s *ManifestScanner) IsAvailable() bool {
+	_, err := exec.LookPath(s.manifest.Binary)
+	return err == nil
+}
+
+// Version returns the tool version using the manifest's version_args
+func (s *ManifestScanner) Version(ctx context.Context) (string, error) {
+	if len(s.manifest.VersionArgs) == 0 {
+		return "", fmt.Errorf("manifest %q declares no version_args", s.manifest.Name)
+	}
+	return toolVersion(ctx, s.manifest.Binary, s.manifest.VersionArgs...)
+}
+
+// CheckData checks that the manifest's data_paths exist
+func (s *ManifestScanner) CheckData(ctx context.Context) (string, error) {
+	return checkDataPaths(s.manifest.DataPaths)
+}
+
+// Scan runs the tool and maps its output to findings
+func (s *ManifestScanner) Scan(ctx context.Context, sourceDir string) ([]*pb.Finding, error) {
+	s.logger.WithField("source_dir", sourceDir).Info("Starting manifest scanner")
+
+	if !s.IsAvailable() {
+		return nil, fmt.Errorf("%s is not installed", s.manifest.Binary)
+	}
+
+	output, err := runTemplateCommand(ctx, s.logger, s.manifest.Name, s.manifest.Binary, s.manifest.Args, s.manifest.SuccessExitCodes, sourceDir)
+	if err != nil {
+		return nil, err
+	}
+
+	findings, err := s.parseOutput(output)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s results: %w", s.manifest.Name, err)
+	}
+
+	s.logger.WithField("findings", len(findings)).Info("Manifest scanner complete")
+	return findings, nil
+}
+
+// checkDataPaths checks that the data files or directories of a tool exist
+func checkDataPaths(paths []string) (string, error) {
+	if len(paths) == 0 {
+		return "", nil
+	}
+	for _, path := range paths {
+		if _, err := os.Stat(path); err != nil {
+			return "", fmt.Errorf("missing data: %w", err)
+		}
+	}
+	return strings.Join(paths, ", "), nil
+}
+
+// runTemplateCommand substitutes the argument placeholders, runs the command
+// and returns the results from the output file, or stdout if none is used.
+// A run fails if its exit code is not one of successCodes or, without
+// successCodes, if it exits non-zero without output, so a crashed tool is
+// not mistaken for a clean scan.
+func runTemplateCommand(ctx context.Context, logger *log.Entry, name, binary string, argTemplate []string, successCodes []int, sourceDir string) ([]byte, error) {
+	useOutputFile := false
+	for _, arg := range argTemplate {
+		if strings.Contains(arg, placeholderOutputFile) {
+			useOutputFile = true
+			break
+		}
+	}
+
+	var resultsFile string
+	if useOutputFile {
+		f, err := os.CreateTemp("", name+"-results-*")
+		if err != nil {
+			return nil, fmt.Errorf("failed to create results file: %w", err)
+		}
+		resultsFile = f.Name()
+		f.Close()
+		defer os.Remove(resultsFile)
+	}
+
+	args := make([]string, len(argTemplate))
+	for i, arg := range argTemplate {
+		arg = strings.ReplaceAll(arg, placeholderSourceDir, sourceDir)
+		arg = strings.ReplaceAll(arg, placeholderOutputFile, resultsFile)
+		args[i] = arg
+	}
+
+	cmd := exec.CommandContext(ctx, binary, args...)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	exitCode := 0
+	if err := cmd.Run(); err != nil {
+		var exitErr *exec.ExitError
+		if !errors.As(err, &exitErr) || exitErr.ExitCode() < 0 || ctx.Err() != nil {
+			return nil, fmt.Errorf("failed to run %s: %w", name, err)
+		}
+		exitCode = exitErr.ExitCode()
+	}
+	if len(successCodes) > 0 && !slices.Contains(successCodes, exitCode) {
+		return nil, exitCodeError(name, exitCode, "", stderr.String())
+	}
+
+	data := stdout.Bytes()
+	if useOutputFile {
+		var err error
+		data, err = os.ReadFile(resultsFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read results: %w", err)
+		}
+	}
+
+	if exitCode != 0 && len(successCodes) == 0 {
+		if len(bytes.TrimSpace(data)) == 0 {
+			return nil, exitCodeError(name, exitCode, " and no output", stderr.String())
+		}
+		// Most tools exit non-zero when they report findings
+		logger.WithField("exit_code", exitCode).WithField("stderr", truncate(stderr.String(), 2048)).Warn("Scanner exited with error (may have findings)")
+	}
+	return data, nil
+}
+
+// exitCodeError reports a failed run with the end of its stderr
+func exitCodeError(name string, exitCode int, reason, stderr string) error {
+	if stderr = strings.TrimSpace(stderr); stderr != "" {
+		return fmt.Errorf("%s exited with code %d%s: %s", name, exitCode, reason, truncate(stderr, 2048))
+	}
+	return fmt.Errorf("%s exited with code %d%s", name, exitCode, reason)
+}
+
+// parseOutput decodes the tool output and maps each result to a finding
+func (s *ManifestScanner) parseOutput(data []byte) ([]*pb.Finding, error) {
+	var results []interface{}
+
+	switch s.manifest.Output.Format {
+	case FormatJSONL:
+		scanner := bufio.NewScanner(bytes.NewReader(data))
+		scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+		for scanner.Scan() {
+			line := bytes.TrimSpace(scanner.Bytes())
+			if len(line) == 0 {
+				continue
+			}
+			var doc interface{}
+			if err := json.Unmarshal(line, &doc); err != nil {
+				s.logger.WithError(err).Warn("Failed to parse output line")
+				continue
+			}
+			results = append(results, resultsAt(doc, s.manifest.Output.Results)...)
+		}
+		if err := scanner.Err(); err != nil {
+			return nil, fmt.Errorf("failed to read output: %w", err)
+		}
+
+	default:
+		if len(bytes.TrimSpace(data)) == 0 {
+			return nil, fmt.Errorf("tool produced no output")
+		}
+		var doc interface{}
+		if err := json.Unmarshal(data, &doc); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal JSON: %w", err)
+		}
+		results = resultsAt(doc, s.manifest.Output.Results)
+	}
+
+	findings := make([]*pb.Finding, 0, len(results))
+	for _, r := range results {
+		findings = append(findings, s.mapResult(r))
+	}
+	return findings, nil
+}
+
+// resultsAt returns the results found at path; a single object counts as one result
+func resultsAt(doc interface{}, path string) []interface{} {
+	value, ok := lookupPath(doc, path)
+	if !ok || value == nil {
+		return nil
+	}
+	if list, ok := value.([]interface{}); ok {
+		return list
+	}
+	return []interface{}{value}
+}
+
+// mapResult maps one result object onto a finding using the manifest fields
+func (s *ManifestScanner) mapResult(result interface{}) *pb.Finding {
+	fields := s.manifest.Fields
+
+	finding := &pb.Finding{
+		ScanType:    s.ScanType(),
+		Severity:    s.mapSeverity(extractField(result, fields.Severity)),
+		Title:       extractField(result, fields.Title),
+		Description: extractField(result, fields.Description),
+		FilePath:    extractField(result, fields.FilePath),
+		LineNumber:  parseLineNumber(extractField(result, fields.LineNumber)),
+		CodeSnippet: extractField(result, fields.CodeSnippet),
+		CveId:       extractField(result, fields.CveID),
+		CweId:       extractField(result, fields.CweID),
+	}
+
+	if fields.References != "" {
+		if value, ok := lookupPath(result, fields.References); ok {
+			finding.References = stringList(value)
+		}
+	}
+
+	return finding
+}
+
+// mapSeverity maps a tool severity through the manifest's severity map
+func (s *ManifestScanner) mapSeverity(value string) pb.Severity {
+	if value == "" {
+		return s.manifest.defaultSeverity
+	}
+	if severity, ok := s.manifest.severityMap[strings.ToUpper(value)]; ok {
+		return severity
+	}
+	if severity, ok := parseSeverity(value); ok {
+		return severity
+	}
+	return s.manifest.defaultSeverity
+}
+
+// extractField resolves a field mapping (path or template) against a result
+func extractField(result interface{}, mapping string) string {
+	if mapping == "" {
+		return ""
+	}
+
+	if !strings.Contains(mapping, "{{") {
+		value, _ := lookupPath(result, mapping)
+		return stringValue(value)
+	}
+
+	return fieldTemplatePattern.ReplaceAllStringFunc(mapping, func(match string) string {
+		path := fieldTemplatePattern.FindStringSubmatch(match)[1]
+		value, _ := lookupPath(result, path)
+		return stringValue(value)
+	})
+}
+
+// lookupPath walks a dotted path (object keys and array indices) through decoded JSON
+func lookupPath(value interface{}, path string) (interface{}, bool) {
+	if path == "" {
+		return value, true
+	}
+
+	for _, key := range strings.Split(path, ".") {
+		switch v := value.(type) {
+		case map[string]interface{}:
+			next, ok := v[key]
+			if !ok {
+				return nil, false
+			}
+			value = next
+		case []interface{}:
+			idx, err := strconv.Atoi(key)
+			if err != nil || idx < 0 || idx >= len(v) {
+				return nil, false
+			}
+			value = v[idx]
+		default:
+			return nil, false
+		}
+	}
+
+	return value, true
+}
+
+// stringValue renders a decoded JSON value as a string; arrays yield their first element
+func stringValue(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	case []interface{}:
+		if len(v) == 0 {
+			return ""
+		}
+		return stringValue(v[0])
+	default:
+		data, err := json.Marshal(v)
+		if err != nil {
+			return ""
+		}
+		return string(data)
+	}
+}
+
+// stringList renders a decoded JSON value as a list of strings
+func stringList(value interface{}) []string {
+	list, ok := value.([]interface{})
+	if !ok {
+		if s := stringValue(value); s != "" {
+			return []string{s}
+		}
+		return nil
+	}
+
+	out := make([]string, 0, len(list))
+	for _, item := range list {
+		if s := stringValue(item); s != "" {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+// parseLineNumber extracts the leading line number from values like "12" or "12-14"
+func parseLineNumber(value string) int32 {
+	value = strings.TrimSpace(value)
+	end := 0
+	for end < len(value) && value[end] >= '0' && value[end] <= '9' {
+		end++
+	}
+	n, err := strconv.Atoi(value[:end])
+	if err != nil {
+		return 0
+	}
+	return int32(n)
+}
+
+// truncate shortens s to at most n bytes
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n] + "..."
+}
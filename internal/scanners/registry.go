@@ -0,0 +1,255 @@
+package scanners
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	pb "github.com/cloud-scan/cloudscan-orchestrator/generated/proto"
+	log "github.com/sirupsen/logrus"
+)
+
+// SelectionMode controls how many scanners are picked per scan type
+type SelectionMode string
+
+const (
+	// SelectBest picks the highest-priority available scanner for each scan type
+	SelectBest SelectionMode = "best"
+	// SelectAll picks every available scanner for each scan type
+	SelectAll SelectionMode = "all"
+)
+
+// ParseSelectionMode parses a selection mode name
+func ParseSelectionMode(mode string) (SelectionMode, error) {
+	switch SelectionMode(strings.ToLower(strings.TrimSpace(mode))) {
+	case SelectBest, "":
+		return SelectBest, nil
+	case SelectAll:
+		return SelectAll, nil
+	default:
+		return "", fmt.Errorf("unknown scanner selection mode %q (expected %q or %q)", mode, SelectBest, SelectAll)
+	}
+}
+
+// SourceKind identifies what a scanner analyzes
+type SourceKind int
+
+const (
+	// SourceCode scanners analyze a source tree
+	SourceCode SourceKind = iota
+	// SourceSBOM scanners analyze a CycloneDX or SPDX document
+	SourceSBOM
+)
+
+// String returns the source kind name
+func (k SourceKind) String() string {
+	switch k {
+	case SourceCode:
+		return "code"
+	case SourceSBOM:
+		return "sbom"
+	default:
+		return "unknown"
+	}
+}
+
+// ParseSourceKind parses a source kind name
+func ParseSourceKind(name string) (SourceKind, error) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "", "code":
+		return SourceCode, nil
+	case "sbom":
+		return SourceSBOM, nil
+	default:
+		return SourceCode, fmt.Errorf("unknown source kind %q (expected \"code\" or \"sbom\")", name)
+	}
+}
+
+// Registration describes a scanner adapter known to the registry
+type Registration struct {
+	// Name uniquely identifies the adapter
+	Name string
+
+	// ScanTypes lists the scan types the adapter serves
+	ScanTypes []pb.ScanType
+
+	// Priority orders adapters serving the same scan type (higher wins)
+	Priority int
+
+	// Source is what the adapter analyzes (source code unless set)
+	Source SourceKind
+
+	// MinVersion is the oldest tool version the adapter supports (optional)
+	MinVersion string
+
+	// New constructs a scanner instance
+	New func() Scanner
+}
+
+var (
+	registryMu sync.RWMutex
+	registry   = make(map[string]Registration)
+)
+
+// Register adds a scanner adapter to the registry
+func Register(reg Registration) error {
+	if reg.Name == "" {
+		return fmt.Errorf("scanner registration requires a name")
+	}
+	if reg.New == nil {
+		return fmt.Errorf("scanner %q has no constructor", reg.Name)
+	}
+	if len(reg.ScanTypes) == 0 {
+		return fmt.Errorf("scanner %q serves no scan types", reg.Name)
+	}
+
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	if _, exists := registry[reg.Name]; exists {
+		return fmt.Errorf("scanner %q is already registered", reg.Name)
+	}
+	registry[reg.Name] = reg
+	return nil
+}
+
+// mustRegister registers a built-in adapter and panics on conflict
+func mustRegister(reg Registration) {
+	if err := Register(reg); err != nil {
+		panic(err)
+	}
+}
+
+// Registrations returns all registered adapters ordered by name
+func Registrations() []Registration {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	regs := make([]Registration, 0, len(registry))
+	for _, reg := range registry {
+		regs = append(regs, reg)
+	}
+	sort.Slice(regs, func(i, j int) bool { return regs[i].Name < regs[j].Name })
+	return regs
+}
+
+// candidatesFor returns adapters serving a scan type for a source kind,
+// highest priority first
+func candidatesFor(scanType pb.ScanType, source SourceKind) []Registration {
+	var candidates []Registration
+	for _, reg := range Registrations() {
+		if reg.Source != source {
+			continue
+		}
+		for _, st := range reg.ScanTypes {
+			if st == scanType {
+				candidates = append(candidates, reg)
+				break
+			}
+		}
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].Priority > candidates[j].Priority
+	})
+	return candidates
+}
+
+// ParseScanType converts a scan type name such as "sast" to its proto value
+func ParseScanType(name string) (pb.ScanType, bool) {
+	value, ok := pb.ScanType_value[strings.ToUpper(strings.TrimSpace(name))]
+	if !ok || pb.ScanType(value) == pb.ScanType_SCAN_TYPE_UNSPECIFIED {
+		return pb.ScanType_SCAN_TYPE_UNSPECIFIED, false
+	}
+	return pb.ScanType(value), true
+}
+
+// SelectionFailure explains why a requested scan type cannot be served
+type SelectionFailure struct {
+	ScanType string
+	Reason   string
+}
+
+// SelectionError reports every requested scan type that could not be served
+type SelectionError struct {
+	Failures []SelectionFailure
+}
+
+func (e *SelectionError) Error() string {
+	parts := make([]string, 0, len(e.Failures))
+	for _, f := range e.Failures {
+		parts = append(parts, fmt.Sprintf("%s: %s", f.ScanType, f.Reason))
+	}
+	return "unserved scan types: " + strings.Join(parts, "; ")
+}
+
+// Select resolves requested scan types to scanner instances for a source kind.
+// Scanners are returned for every type that can be served; the error is a
+// *SelectionError listing the types that could not be.
+func Select(scanTypes []string, mode SelectionMode, source SourceKind) ([]Scanner, error) {
+	var selected []Scanner
+	var failures []SelectionFailure
+	chosen := make(map[string]bool)
+
+	for _, name := range scanTypes {
+		if strings.TrimSpace(name) == "" {
+			continue
+		}
+
+		scanType, ok := ParseScanType(name)
+		if !ok {
+			failures = append(failures, SelectionFailure{ScanType: name, Reason: "unknown scan type"})
+			continue
+		}
+
+		candidates := candidatesFor(scanType, source)
+		if len(candidates) == 0 {
+			failures = append(failures, SelectionFailure{
+				ScanType: name,
+				Reason:   fmt.Sprintf("no scanner registered for %s sources", source),
+			})
+			continue
+		}
+
+		var unavailable []string
+		served := false
+		for _, reg := range candidates {
+			if chosen[reg.Name] {
+				served = true
+				if mode == SelectBest {
+					break
+				}
+				continue
+			}
+
+			scanner := reg.New()
+			if !scanner.IsAvailable() {
+				log.WithFields(log.Fields{
+					"scanner":   reg.Name,
+					"scan_type": scanType,
+				}).Warn("Scanner not available")
+				unavailable = append(unavailable, reg.Name)
+				continue
+			}
+
+			chosen[reg.Name] = true
+			selected = append(selected, scanner)
+			served = true
+			if mode == SelectBest {
+				break
+			}
+		}
+
+		if !served {
+			failures = append(failures, SelectionFailure{
+				ScanType: name,
+				Reason:   fmt.Sprintf("no available scanner (not installed: %s)", strings.Join(unavailable, ", ")),
+			})
+		}
+	}
+
+	if len(failures) > 0 {
+		return selected, &SelectionError{Failures: failures}
+	}
+	return selected, nil
+}
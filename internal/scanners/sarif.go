@@ -0,0 +1,465 @@
+package scanners
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	pb "github.com/cloud-scan/cloudscan-orchestrator/generated/proto"
+	"github.com/cloud-scan/cloudscan-runner/internal/sarif"
+	log "github.com/sirupsen/logrus"
+)
+
+var (
+	cwePattern = regexp.MustCompile(`(?i)\bcwe[-_:/ ]?0*(\d+)\b`)
+	cvePattern = regexp.MustCompile(`(?i)\bCVE-\d{4}-\d{4,}\b`)
+)
+
+// maxURIBaseDepth bounds originalUriBaseIds chains to avoid reference loops
+const maxURIBaseDepth = 8
+
+// SARIFScanner runs any command that emits SARIF 2.1.0 and ingests its output
+type SARIFScanner struct {
+	name        string
+	scanType    pb.ScanType
+	binary      string
+	args        []string
+	versionArgs []string
+	dataPaths   []string
+	logger      *log.Entry
+
+	successExitCodes []int // Exit codes of a successful run; any with output when empty
+}
+
+// NewSARIFScanner creates a scanner wrapping a SARIF-emitting command.
+// The args may use the {{sourceDir}} and {{outputFile}} placeholders; SARIF is
+// read from {{outputFile}} when referenced, otherwise from stdout.
+func NewSARIFScanner(name string, scanType pb.ScanType, binary string, args []string) *SARIFScanner {
+	return &SARIFScanner{
+		name:     name,
+		scanType: scanType,
+		binary:   binary,
+		args:     args,
+		logger:   log.WithField("scanner", name),
+	}
+}
+
+// Name returns the scanner name
+func (s *SARIFScanner) Name() string {
+	return s.name
+}
+
+// ScanType returns the scan type
+func (s *SARIFScanner) ScanType() pb.ScanType {
+	return s.scanType
+}
+
+// IsAvailable checks if the wrapped command is installed
+func (s *SARIFScanner) IsAvailable() bool {
+	_, err := exec.LookPath(s.binary)
+	return err == nil
+}
+
+// Version returns the wrapped tool's version when version arguments are known
+func (s *SARIFScanner) Version(ctx context.Context) (string, error) {
+	if len(s.versionArgs) == 0 {
+		return "", fmt.Errorf("no version command known for %s", s.name)
+	}
+	return toolVersion(ctx, s.binary, s.versionArgs...)
+}
+
+// CheckData checks that the data files the wrapped tool needs exist
+func (s *SARIFScanner) CheckData(ctx context.Context) (string, error) {
+	return checkDataPaths(s.dataPaths)
+}
+
+// Scan runs the wrapped command and parses its SARIF output
+func (s *SARIFScanner) Scan(ctx context.Context, sourceDir string) ([]*pb.Finding, error) {
+	s.logger.WithField("source_dir", sourceDir).Info("Starting SARIF scan")
+
+	if !s.IsAvailable() {
+		return nil, fmt.Errorf("%s is not installed", s.binary)
+	}
+
+	output, err := runTemplateCommand(ctx, s.logger, s.name, s.binary, s.args, s.successExitCodes, sourceDir)
+	if err != nil {
+		return nil, err
+	}
+
+	findings, err := ParseSARIF(output, s.ScanType(), sourceDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s results: %w", s.name, err)
+	}
+
+	s.logger.WithField("findings", len(findings)).Info("SARIF scan complete")
+	return findings, nil
+}
+
+// ParseSARIF maps every result of every run in a SARIF 2.1.0 log onto findings.
+// File paths under sourceDir are reported relative to it.
+func ParseSARIF(data []byte, scanType pb.ScanType, sourceDir string) ([]*pb.Finding, error) {
+	var doc sarif.Log
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal SARIF: %w", err)
+	}
+
+	findings := make([]*pb.Finding, 0)
+	for _, run := range doc.Runs {
+		if run == nil {
+			continue
+		}
+		p := &sarifRunParser{run: run, sourceDir: sourceDir}
+		for _, result := range run.Results {
+			if result == nil {
+				continue
+			}
+			findings = append(findings, p.mapResult(result, scanType))
+		}
+	}
+
+	return findings, nil
+}
+
+// sarifRunParser maps results within the context of a single run
+type sarifRunParser struct {
+	run       *sarif.Run
+	sourceDir string
+}
+
+// mapResult converts one SARIF result into a finding
+func (p *sarifRunParser) mapResult(result *sarif.Result, scanType pb.ScanType) *pb.Finding {
+	rule := p.lookupRule(result)
+
+	ruleID := result.RuleID
+	if ruleID == "" && result.Rule != nil {
+		ruleID = result.Rule.ID
+	}
+	if ruleID == "" && rule != nil {
+		ruleID = rule.ID
+	}
+
+	finding := &pb.Finding{
+		ScanType:    scanType,
+		Severity:    sarifSeverity(result, rule),
+		Title:       ruleID,
+		Description: p.describe(result, rule),
+	}
+	if finding.Title == "" {
+		finding.Title = firstLine(result.Message.Text)
+	}
+
+	if len(result.Locations) > 0 && result.Locations[0].PhysicalLocation != nil {
+		loc := result.Locations[0].PhysicalLocation
+		finding.FilePath = p.resolvePath(loc.ArtifactLocation)
+		if loc.Region != nil {
+			finding.LineNumber = int32(loc.Region.StartLine)
+			setLocationDetails(finding, loc.Region.StartColumn, loc.Region.EndLine, loc.Region.EndColumn)
+			if loc.Region.Snippet != nil {
+				finding.CodeSnippet = loc.Region.Snippet.Text
+			}
+		}
+		if finding.CodeSnippet == "" && loc.ContextRegion != nil && loc.ContextRegion.Snippet != nil {
+			finding.CodeSnippet = loc.ContextRegion.Snippet.Text
+		}
+	}
+
+	tags := result.Properties.Strings("tags")
+	if rule != nil {
+		tags = append(tags, rule.Properties.Strings("tags")...)
+	}
+	finding.CweId = findCWE(tags)
+	finding.CveId = findCVE(append([]string{ruleID}, tags...))
+
+	if rule != nil && rule.HelpURI != "" {
+		finding.References = append(finding.References, rule.HelpURI)
+	}
+
+	return finding
+}
+
+// lookupRule finds the rule metadata for a result by index or ID
+func (p *sarifRunParser) lookupRule(result *sarif.Result) *sarif.ReportingDescriptor {
+	rules := p.run.Tool.Driver.Rules
+
+	// Rules may live in an extension (rule pack) rather than the driver
+	if result.Rule != nil && result.Rule.ToolComponent != nil && result.Rule.ToolComponent.Index != nil {
+		idx := *result.Rule.ToolComponent.Index
+		if idx >= 0 && idx < len(p.run.Tool.Extensions) && p.run.Tool.Extensions[idx] != nil {
+			rules = p.run.Tool.Extensions[idx].Rules
+		}
+	}
+
+	index := result.RuleIndex
+	if index == nil && result.Rule != nil {
+		index = result.Rule.Index
+	}
+	if index != nil && *index >= 0 && *index < len(rules) {
+		return rules[*index]
+	}
+
+	id := result.RuleID
+	if id == "" && result.Rule != nil {
+		id = result.Rule.ID
+	}
+	if id == "" {
+		return nil
+	}
+
+	candidates := [][]*sarif.ReportingDescriptor{p.run.Tool.Driver.Rules}
+	for _, ext := range p.run.Tool.Extensions {
+		if ext != nil {
+			candidates = append(candidates, ext.Rules)
+		}
+	}
+	for _, list := range candidates {
+		for _, r := range list {
+			// Hierarchical rule IDs ("a/b") may report only the parent in the rule table
+			if r != nil && (r.ID == id || strings.HasPrefix(id, r.ID+"/")) {
+				return r
+			}
+		}
+	}
+	return nil
+}
+
+// describe builds the finding description from the message, rule text and code flows
+func (p *sarifRunParser) describe(result *sarif.Result, rule *sarif.ReportingDescriptor) string {
+	description := result.Message.Text
+	if description == "" {
+		description = result.Message.Markdown
+	}
+
+	if rule != nil {
+		ruleText := ""
+		if rule.FullDescription != nil {
+			ruleText = rule.FullDescription.Text
+		}
+		if ruleText == "" && rule.ShortDescription != nil {
+			ruleText = rule.ShortDescription.Text
+		}
+		if ruleText != "" && ruleText != description {
+			if description == "" {
+				description = ruleText
+			} else {
+				description += "\n\n" + ruleText
+			}
+		}
+	}
+
+	if flow := p.describeCodeFlow(result); flow != "" {
+		description += "\n\n" + flow
+	}
+
+	return description
+}
+
+// describeCodeFlow renders the first code flow as a numbered list of steps
+func (p *sarifRunParser) describeCodeFlow(result *sarif.Result) string {
+	for _, flow := range result.CodeFlows {
+		if flow == nil {
+			continue
+		}
+		for _, thread := range flow.ThreadFlows {
+			if thread == nil || len(thread.Locations) == 0 {
+				continue
+			}
+
+			var b strings.Builder
+			b.WriteString("Code flow:")
+			for i, step := range thread.Locations {
+				if step == nil || step.Location == nil {
+					continue
+				}
+				fmt.Fprintf(&b, "\n%d. %s", i+1, p.describeLocation(step.Location))
+			}
+			return b.String()
+		}
+	}
+	return ""
+}
+
+// describeLocation renders a location as "path:line - message"
+func (p *sarifRunParser) describeLocation(loc *sarif.Location) string {
+	var parts []string
+	if loc.PhysicalLocation != nil {
+		where := p.resolvePath(loc.PhysicalLocation.ArtifactLocation)
+		if loc.PhysicalLocation.Region != nil && loc.PhysicalLocation.Region.StartLine > 0 {
+			where += ":" + strconv.Itoa(loc.PhysicalLocation.Region.StartLine)
+		}
+		parts = append(parts, where)
+	}
+	if loc.Message != nil && loc.Message.Text != "" {
+		parts = append(parts, loc.Message.Text)
+	}
+	return strings.Join(parts, " - ")
+}
+
+// resolvePath resolves an artifact location, following artifact indices and
+// originalUriBaseIds, to a file path
+func (p *sarifRunParser) resolvePath(loc *sarif.ArtifactLocation) string {
+	if loc == nil {
+		return ""
+	}
+
+	if loc.URI == "" && loc.Index != nil && *loc.Index >= 0 && *loc.Index < len(p.run.Artifacts) {
+		if artifact := p.run.Artifacts[*loc.Index]; artifact != nil && artifact.Location != nil {
+			loc = artifact.Location
+		}
+	}
+
+	uri := loc.URI
+	baseID := loc.URIBaseID
+	for depth := 0; baseID != "" && depth < maxURIBaseDepth && !isAbsoluteURI(uri); depth++ {
+		base, ok := p.run.OriginalURIBaseIDs[baseID]
+		if !ok || base == nil {
+			break
+		}
+		uri = joinURI(base.URI, uri)
+		baseID = base.URIBaseID
+	}
+
+	return p.relativize(uriToPath(uri))
+}
+
+// relativize makes absolute paths under the source directory relative to it
+func (p *sarifRunParser) relativize(path string) string {
+	if path == "" || p.sourceDir == "" || !filepath.IsAbs(path) {
+		return path
+	}
+	rel, err := filepath.Rel(p.sourceDir, path)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, "../") {
+		return path
+	}
+	return rel
+}
+
+// isAbsoluteURI reports whether uri has a scheme (e.g. file:///src/a.go)
+func isAbsoluteURI(uri string) bool {
+	u, err := url.Parse(uri)
+	return err == nil && u.Scheme != ""
+}
+
+// joinURI resolves a relative URI reference against a base URI
+func joinURI(base, ref string) string {
+	if base == "" {
+		return ref
+	}
+	if !strings.HasSuffix(base, "/") {
+		base += "/"
+	}
+	if !isAbsoluteURI(base) {
+		// Relative bases chain onto a further uriBaseId; resolve them textually
+		return base + strings.TrimPrefix(ref, "./")
+	}
+	baseURL, err := url.Parse(base)
+	if err != nil {
+		return base + ref
+	}
+	refURL, err := url.Parse(ref)
+	if err != nil {
+		return base + ref
+	}
+	return baseURL.ResolveReference(refURL).String()
+}
+
+// uriToPath converts a file URI or relative URI reference to a file path
+func uriToPath(uri string) string {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return uri
+	}
+	if u.Scheme == "file" {
+		return filepath.FromSlash(u.Path)
+	}
+	if u.Scheme != "" {
+		return uri
+	}
+
+	path, err := url.PathUnescape(u.Path)
+	if err != nil {
+		return u.Path
+	}
+	return filepath.FromSlash(path)
+}
+
+// sarifSeverity derives a severity from the security-severity score when
+// present, falling back to the result or rule level
+func sarifSeverity(result *sarif.Result, rule *sarif.ReportingDescriptor) pb.Severity {
+	score := result.Properties.String("security-severity")
+	if score == "" && rule != nil {
+		score = rule.Properties.String("security-severity")
+	}
+	if score != "" {
+		if value, err := strconv.ParseFloat(score, 64); err == nil {
+			return severityFromScore(value)
+		}
+	}
+
+	level := result.Level
+	if level == "" && rule != nil && rule.DefaultConfiguration != nil {
+		level = rule.DefaultConfiguration.Level
+	}
+
+	switch level {
+	case "error":
+		return pb.Severity_HIGH
+	case "warning", "":
+		return pb.Severity_MEDIUM
+	case "note":
+		return pb.Severity_LOW
+	case "none":
+		return pb.Severity_INFO
+	default:
+		return pb.Severity_MEDIUM
+	}
+}
+
+// severityFromScore maps a CVSS-style 0-10 score onto a severity
+func severityFromScore(score float64) pb.Severity {
+	switch {
+	case score >= 9.0:
+		return pb.Severity_CRITICAL
+	case score >= 7.0:
+		return pb.Severity_HIGH
+	case score >= 4.0:
+		return pb.Severity_MEDIUM
+	case score > 0:
+		return pb.Severity_LOW
+	default:
+		return pb.Severity_INFO
+	}
+}
+
+// findCWE returns the first CWE referenced by tags, formatted as CWE-<n>
+func findCWE(tags []string) string {
+	for _, tag := range tags {
+		if m := cwePattern.FindStringSubmatch(tag); m != nil {
+			return "CWE-" + m[1]
+		}
+	}
+	return ""
+}
+
+// findCVE returns the first CVE ID found in values
+func findCVE(values []string) string {
+	for _, v := range values {
+		if m := cvePattern.FindString(v); m != "" {
+			return strings.ToUpper(m)
+		}
+	}
+	return ""
+}
+
+// firstLine returns the first line of s
+func firstLine(s string) string {
+	if i := strings.IndexByte(s, '\n'); i >= 0 {
+		return s[:i]
+	}
+	return s
+}
@@ -7,11 +7,25 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"strconv"
 
 	pb "github.com/cloud-scan/cloudscan-orchestrator/generated/proto"
+	findingsutil "github.com/cloud-scan/cloudscan-runner/internal/findings"
 	log "github.com/sirupsen/logrus"
 )
 
+// maxCopyrightDetails caps the copyright statements recorded per finding
+const maxCopyrightDetails = 5
+
+func init() {
+	mustRegister(Registration{
+		Name:      "scancode",
+		ScanTypes: []pb.ScanType{pb.ScanType_LICENSE},
+		Priority:  100,
+		New:       func() Scanner { return NewScanCodeScanner() },
+	})
+}
+
 // ScanCodeScanner implements license compliance scanning using ScanCode
 type ScanCodeScanner struct {
 	logger *log.Entry
@@ -40,6 +54,11 @@ func (s *ScanCodeScanner) IsAvailable() bool {
 	return err == nil
 }
 
+// Version returns the installed scancode version
+func (s *ScanCodeScanner) Version(ctx context.Context) (string, error) {
+	return toolVersion(ctx, "scancode", "--version")
+}
+
 // Scan executes ScanCode scan
 func (s *ScanCodeScanner) Scan(ctx context.Context, sourceDir string) ([]*pb.Finding, error) {
 	s.logger.WithField("source_dir", sourceDir).Info("Starting ScanCode scan")
@@ -91,9 +110,21 @@ func (s *ScanCodeScanner) parseResults(resultsFile string) ([]*pb.Finding, error
 			Licenses []struct {
 				Key       string  `json:"key"`
 				ShortName string  `json:"short_name"`
+				SPDXKey   string  `json:"spdx_license_key"`
 				Name      string  `json:"name"`
 				Category  string  `json:"category"`
 				Score     float64 `json:"score"`
+				Owner     string  `json:"owner"`
+				StartLine int     `json:"start_line"`
+				EndLine   int     `json:"end_line"`
+				MatchedRule struct {
+					Identifier string `json:"identifier"`
+				} `json:"matched_rule"`
+				SPDXURL         string `json:"spdx_url"`
+				ReferenceURL    string `json:"reference_url"`
+				HomepageURL     string `json:"homepage_url"`
+				TextURL         string `json:"text_url"`
+				ScancodeTextURL string `json:"scancode_text_url"`
 			} `json:"licenses"`
 			Copyrights []struct {
 				Value string `json:"value"`
@@ -111,11 +142,13 @@ func (s *ScanCodeScanner) parseResults(resultsFile string) ([]*pb.Finding, error
 		for _, license := range file.Licenses {
 			severity := s.getLicenseSeverity(license.Category)
 
-			title := fmt.Sprintf("License: %s", license.ShortName)
-			description := fmt.Sprintf("License '%s' detected in file", license.Name)
-			if license.Category != "" {
-				description += fmt.Sprintf(" (Category: %s)", license.Category)
+			// Prefer the SPDX identifier so policies can name licenses portably
+			name := license.SPDXKey
+			if name == "" {
+				name = license.ShortName
 			}
+			title := fmt.Sprintf("License: %s", name)
+			description := fmt.Sprintf("License '%s' detected in file", license.Name)
 
 			finding := &pb.Finding{
 				ScanType:    pb.ScanType_LICENSE,
@@ -123,6 +156,42 @@ func (s *ScanCodeScanner) parseResults(resultsFile string) ([]*pb.Finding, error
 				Title:       title,
 				Description: description,
 				FilePath:    file.Path,
+				LineNumber:  int32(license.StartLine),
+			}
+			findingsutil.AddReferences(finding, license.SPDXURL, license.ReferenceURL, license.HomepageURL,
+				license.TextURL, license.ScancodeTextURL)
+
+			if license.Category != "" {
+				findingsutil.SetDetail(finding, findingsutil.DetailLicenseCategory, license.Category)
+			}
+			if license.EndLine > license.StartLine {
+				findingsutil.SetDetail(finding, findingsutil.DetailEndLine, strconv.Itoa(license.EndLine))
+			}
+			for _, detail := range []struct {
+				key   string
+				value string
+			}{
+				{"license_key", license.Key},
+				{"owner", license.Owner},
+				{"matched_rule", license.MatchedRule.Identifier},
+			} {
+				if detail.value != "" {
+					findingsutil.SetDetail(finding, detail.key, detail.value)
+				}
+			}
+			if license.Score > 0 {
+				findingsutil.SetDetail(finding, "match_score", strconv.FormatFloat(license.Score, 'f', -1, 64))
+			}
+			if len(file.Copyrights) > 0 {
+				copyrights := make([]string, 0, maxCopyrightDetails+1)
+				for i, c := range file.Copyrights {
+					if i == maxCopyrightDetails {
+						copyrights = append(copyrights, fmt.Sprintf("and %d more", len(file.Copyrights)-i))
+						break
+					}
+					copyrights = append(copyrights, c.Value)
+				}
+				findingsutil.SetListDetail(finding, "copyright", copyrights)
 			}
 
 			findings = append(findings, finding)
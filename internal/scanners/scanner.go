@@ -2,8 +2,13 @@ package scanners
 
 import (
 	"context"
+	"errors"
+	"path/filepath"
+	"strconv"
+	"time"
 
 	pb "github.com/cloud-scan/cloudscan-orchestrator/generated/proto"
+	findingsutil "github.com/cloud-scan/cloudscan-runner/internal/findings"
 )
 
 // Scanner defines the interface for all security scanners
@@ -21,10 +26,82 @@ type Scanner interface {
 	IsAvailable() bool
 }
 
+// Versioner is implemented by scanners that can report their tool version
+type Versioner interface {
+	// Version returns the version of the underlying tool
+	Version(ctx context.Context) (string, error)
+}
+
+// DataChecker is implemented by scanners that need rules or databases
+// besides their binary
+type DataChecker interface {
+	// CheckData verifies the data is present and describes it; an empty
+	// description and no error mean the scanner needs no data
+	CheckData(ctx context.Context) (string, error)
+}
+
+// ErrDataOnDemand reports scanner data that is missing locally but is
+// downloaded when the scanner runs, which requires network access
+var ErrDataOnDemand = errors.New("data is downloaded when the scanner runs")
+
+// TargetedScanner is implemented by scanners that can limit a scan to a
+// subset of files, such as the files changed by a pull request
+type TargetedScanner interface {
+	// ScanFiles scans the given files, relative to sourceDir
+	ScanFiles(ctx context.Context, sourceDir string, files []string) ([]*pb.Finding, error)
+}
+
+// HistoryScanner is implemented by scanners that can scan every commit of a
+// git repository rather than only the checked-out tree
+type HistoryScanner interface {
+	// ScanHistory scans the commits of the git repository at repoDir
+	ScanHistory(ctx context.Context, repoDir string, opts HistoryOptions) ([]*pb.Finding, error)
+}
+
+// HistoryOptions limits a history scan
+type HistoryOptions struct {
+	SinceCommit string // Only scan commits after this one (incremental scans)
+}
+
+// targetPaths joins files relative to sourceDir
+func targetPaths(sourceDir string, files []string) []string {
+	paths := make([]string, 0, len(files))
+	for _, file := range files {
+		paths = append(paths, filepath.Join(sourceDir, filepath.FromSlash(file)))
+	}
+	return paths
+}
+
+// setLocationDetails records the parts of a location that do not fit the
+// proto (which only has the start line); zero values and an end line equal
+// to the start line are omitted
+func setLocationDetails(finding *pb.Finding, column, endLine, endColumn int) {
+	if endLine == int(finding.LineNumber) {
+		endLine = 0
+	}
+	for _, detail := range []struct {
+		key   string
+		value int
+	}{
+		{findingsutil.DetailColumn, column},
+		{findingsutil.DetailEndLine, endLine},
+		{findingsutil.DetailEndColumn, endColumn},
+	} {
+		if detail.value > 0 {
+			findingsutil.SetDetail(finding, detail.key, strconv.Itoa(detail.value))
+		}
+	}
+}
+
 // Result represents the combined scan results
 type Result struct {
 	Findings     []*pb.Finding
 	ScanType     pb.ScanType
 	ScannerName  string
+	Version      string
+	StartTime    time.Time
+	EndTime      time.Time
 	Error        error
+	Skipped      string // Why the scanner did not run (incremental scans)
+	Incomplete   string // Why the results are partial (time budget)
 }
\ No newline at end of file
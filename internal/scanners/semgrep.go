@@ -4,14 +4,26 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"net/http"
 	"os"
 	"os/exec"
 	"path/filepath"
 
 	pb "github.com/cloud-scan/cloudscan-orchestrator/generated/proto"
+	findingsutil "github.com/cloud-scan/cloudscan-runner/internal/findings"
 	log "github.com/sirupsen/logrus"
 )
 
+func init() {
+	mustRegister(Registration{
+		Name:       "semgrep",
+		ScanTypes:  []pb.ScanType{pb.ScanType_SAST},
+		Priority:   100,
+		MinVersion: "1.0.0",
+		New:        func() Scanner { return NewSemgrepScanner() },
+	})
+}
+
 // SemgrepScanner implements SAST scanning using Semgrep
 type SemgrepScanner struct {
 	logger *log.Entry
@@ -40,10 +52,48 @@ func (s *SemgrepScanner) IsAvailable() bool {
 	return err == nil
 }
 
+// Version returns the installed semgrep version
+func (s *SemgrepScanner) Version(ctx context.Context) (string, error) {
+	return toolVersion(ctx, "semgrep", "--version")
+}
+
+// CheckData checks that the Semgrep registry serving the --config=auto
+// rules is reachable
+func (s *SemgrepScanner) CheckData(ctx context.Context) (string, error) {
+	registry := os.Getenv("SEMGREP_URL")
+	if registry == "" {
+		registry = "https://semgrep.dev"
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, registry, nil)
+	if err != nil {
+		return "", fmt.Errorf("invalid SEMGREP_URL: %w", err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("rule registry %s is unreachable: %w", registry, err)
+	}
+	resp.Body.Close()
+	return "rules fetched from " + registry, nil
+}
+
 // Scan executes Semgrep scan
 func (s *SemgrepScanner) Scan(ctx context.Context, sourceDir string) ([]*pb.Finding, error) {
 	s.logger.WithField("source_dir", sourceDir).Info("Starting Semgrep scan")
+	return s.run(ctx, []string{sourceDir})
+}
+
+// ScanFiles executes a Semgrep scan limited to files relative to sourceDir
+func (s *SemgrepScanner) ScanFiles(ctx context.Context, sourceDir string, files []string) ([]*pb.Finding, error) {
+	s.logger.WithFields(log.Fields{
+		"source_dir": sourceDir,
+		"files":      len(files),
+	}).Info("Starting targeted Semgrep scan")
+	return s.run(ctx, targetPaths(sourceDir, files))
+}
 
+// run executes Semgrep against the given target paths
+func (s *SemgrepScanner) run(ctx context.Context, targets []string) ([]*pb.Finding, error) {
 	if !s.IsAvailable() {
 		return nil, fmt.Errorf("semgrep is not installed")
 	}
@@ -53,14 +103,15 @@ func (s *SemgrepScanner) Scan(ctx context.Context, sourceDir string) ([]*pb.Find
 	defer os.Remove(resultsFile)
 
 	// Run semgrep
-	cmd := exec.CommandContext(ctx, "semgrep",
+	args := []string{
 		"--config=auto",              // Use automatic ruleset
 		"--json",                      // JSON output
 		"--output="+resultsFile,       // Output file
 		"--timeout=0",                 // No timeout per file
 		"--max-memory=0",              // No memory limit
-		sourceDir,                     // Source directory
-	)
+	}
+	args = append(args, targets...) // Source directory or files
+	cmd := exec.CommandContext(ctx, "semgrep", args...)
 
 	output, err := cmd.CombinedOutput()
 	if err != nil {
@@ -101,11 +152,21 @@ func (s *SemgrepScanner) parseResults(resultsFile string) ([]*pb.Finding, error)
 			} `json:"end"`
 			Extra struct {
 				Message  string `json:"message"`
+				Severity string `json:"severity"`
+				Fix      string `json:"fix"`
 				Metadata struct {
-					Severity    string   `json:"severity"`
-					CWE         []string `json:"cwe"`
-					Confidence  string   `json:"confidence"`
-					References  []string `json:"references"`
+					Severity    string      `json:"severity"`
+					CWE         jsonStrings `json:"cwe"`
+					OWASP       jsonStrings `json:"owasp"`
+					Confidence  string      `json:"confidence"`
+					Likelihood  string      `json:"likelihood"`
+					Impact      string      `json:"impact"`
+					Category    string      `json:"category"`
+					Subcategory jsonStrings `json:"subcategory"`
+					Technology  jsonStrings `json:"technology"`
+					References  jsonStrings `json:"references"`
+					Source      string      `json:"source"`
+					Shortlink   string      `json:"shortlink"`
 				} `json:"metadata"`
 				Lines string `json:"lines"`
 			} `json:"extra"`
@@ -118,21 +179,56 @@ func (s *SemgrepScanner) parseResults(resultsFile string) ([]*pb.Finding, error)
 
 	findings := make([]*pb.Finding, 0, len(result.Results))
 	for _, r := range result.Results {
-		severity := s.mapSeverity(r.Extra.Metadata.Severity)
+		// Semgrep reports the rule severity in extra; older rules carry it in metadata
+		severity := r.Extra.Severity
+		if severity == "" {
+			severity = r.Extra.Metadata.Severity
+		}
+		meta := r.Extra.Metadata
 
 		finding := &pb.Finding{
 			ScanType:    pb.ScanType_SAST,
-			Severity:    severity,
+			Severity:    s.mapSeverity(severity),
 			Title:       r.CheckID,
 			Description: r.Extra.Message,
 			FilePath:    r.Path,
 			LineNumber:  int32(r.Start.Line),
-			CodeSnippet: r.Extra.Lines,
 		}
 
-		// Add CWE if available
-		if len(r.Extra.Metadata.CWE) > 0 {
-			finding.CweId = r.Extra.Metadata.CWE[0]
+		// Semgrep replaces the snippet with a placeholder when not logged in
+		if r.Extra.Lines != "requires login" {
+			finding.CodeSnippet = r.Extra.Lines
+		}
+
+		// The first CWE fits the proto; any others go into the details
+		if len(meta.CWE) > 0 {
+			finding.CweId = meta.CWE[0]
+			findingsutil.SetListDetail(finding, findingsutil.DetailCWE, meta.CWE[1:])
+		}
+
+		findingsutil.AddReferences(finding, meta.Source)
+		findingsutil.AddReferences(finding, meta.References...)
+		findingsutil.AddReferences(finding, meta.Shortlink)
+
+		setLocationDetails(finding, r.Start.Col, r.End.Line, r.End.Col)
+		findingsutil.SetListDetail(finding, findingsutil.DetailOWASP, meta.OWASP)
+		for _, detail := range []struct {
+			key   string
+			value string
+		}{
+			{findingsutil.DetailConfidence, meta.Confidence},
+			{"likelihood", meta.Likelihood},
+			{"impact", meta.Impact},
+			{"category", meta.Category},
+		} {
+			if detail.value != "" {
+				findingsutil.SetDetail(finding, detail.key, detail.value)
+			}
+		}
+		findingsutil.SetListDetail(finding, "subcategory", meta.Subcategory)
+		findingsutil.SetListDetail(finding, "technology", meta.Technology)
+		if r.Extra.Fix != "" {
+			findingsutil.AddSection(finding, findingsutil.SectionFix, r.Extra.Fix)
 		}
 
 		findings = append(findings, finding)
@@ -141,6 +237,27 @@ func (s *SemgrepScanner) parseResults(resultsFile string) ([]*pb.Finding, error)
 	return findings, nil
 }
 
+// jsonStrings decodes a JSON string or array of strings, as rule metadata
+// uses both forms
+type jsonStrings []string
+
+// UnmarshalJSON accepts a string, an array of strings or null
+func (j *jsonStrings) UnmarshalJSON(data []byte) error {
+	var list []string
+	if err := json.Unmarshal(data, &list); err == nil {
+		*j = list
+		return nil
+	}
+	var single string
+	if err := json.Unmarshal(data, &single); err != nil {
+		return err
+	}
+	if single != "" {
+		*j = jsonStrings{single}
+	}
+	return nil
+}
+
 // mapSeverity maps Semgrep severity to proto severity
 func (s *SemgrepScanner) mapSeverity(severity string) pb.Severity {
 	switch severity {
@@ -3,15 +3,30 @@ package scanners
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"sort"
+	"strings"
+	"time"
 
 	pb "github.com/cloud-scan/cloudscan-orchestrator/generated/proto"
+	findingsutil "github.com/cloud-scan/cloudscan-runner/internal/findings"
 	log "github.com/sirupsen/logrus"
 )
 
+func init() {
+	mustRegister(Registration{
+		Name:       "trivy",
+		ScanTypes:  []pb.ScanType{pb.ScanType_SCA},
+		Priority:   100,
+		MinVersion: "0.37.0", // --scanners replaced --security-checks
+		New:        func() Scanner { return NewTrivyScanner() },
+	})
+}
+
 // TrivyScanner implements SCA scanning using Trivy
 type TrivyScanner struct {
 	logger *log.Entry
@@ -40,6 +55,44 @@ func (t *TrivyScanner) IsAvailable() bool {
 	return err == nil
 }
 
+// Version returns the installed trivy version
+func (t *TrivyScanner) Version(ctx context.Context) (string, error) {
+	return toolVersion(ctx, "trivy", "--version")
+}
+
+// CheckData checks for the vulnerability database in the Trivy cache
+func (t *TrivyScanner) CheckData(ctx context.Context) (string, error) {
+	cacheDir := os.Getenv("TRIVY_CACHE_DIR")
+	if cacheDir == "" {
+		userCache, err := os.UserCacheDir()
+		if err != nil {
+			return "", fmt.Errorf("failed to locate the trivy cache: %w", err)
+		}
+		cacheDir = filepath.Join(userCache, "trivy")
+	}
+	dbDir := filepath.Join(cacheDir, "db")
+
+	if _, err := os.Stat(filepath.Join(dbDir, "trivy.db")); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return "no vulnerability database in " + dbDir, ErrDataOnDemand
+		}
+		return "", fmt.Errorf("failed to read the vulnerability database: %w", err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dbDir, "metadata.json"))
+	if err != nil {
+		return "", fmt.Errorf("failed to read vulnerability database metadata: %w", err)
+	}
+	var metadata struct {
+		Version   int       `json:"Version"`
+		UpdatedAt time.Time `json:"UpdatedAt"`
+	}
+	if err := json.Unmarshal(data, &metadata); err != nil {
+		return "", fmt.Errorf("failed to parse vulnerability database metadata: %w", err)
+	}
+	return fmt.Sprintf("vulnerability database v%d updated %s", metadata.Version, metadata.UpdatedAt.Format("2006-01-02")), nil
+}
+
 // Scan executes Trivy scan
 func (t *TrivyScanner) Scan(ctx context.Context, sourceDir string) ([]*pb.Finding, error) {
 	t.logger.WithField("source_dir", sourceDir).Info("Starting Trivy scan")
@@ -98,6 +151,19 @@ func (t *TrivyScanner) parseResults(resultsFile string) ([]*pb.Finding, error) {
 				Title            string  `json:"Title"`
 				Description      string  `json:"Description"`
 				References       []string `json:"References"`
+				SeveritySource   string   `json:"SeveritySource"`
+				CVSS             map[string]trivyCVSS `json:"CVSS"`
+				PrimaryURL       string   `json:"PrimaryURL"`
+				CweIDs           []string `json:"CweIDs"`
+				PkgID            string   `json:"PkgID"`
+				PkgPath          string   `json:"PkgPath"`
+				Status           string   `json:"Status"`
+				PublishedDate    string   `json:"PublishedDate"`
+				LastModifiedDate string   `json:"LastModifiedDate"`
+				DataSource       struct {
+					Name string `json:"Name"`
+					URL  string `json:"URL"`
+				} `json:"DataSource"`
 			} `json:"Vulnerabilities"`
 		} `json:"Results"`
 	}
@@ -113,8 +179,8 @@ func (t *TrivyScanner) parseResults(resultsFile string) ([]*pb.Finding, error) {
 
 			title := fmt.Sprintf("%s in %s@%s", v.VulnerabilityID, v.PkgName, v.InstalledVersion)
 			description := v.Description
-			if v.FixedVersion != "" {
-				description += fmt.Sprintf("\n\nFixed in version: %s", v.FixedVersion)
+			if v.Title != "" && !strings.HasPrefix(description, v.Title) {
+				description = strings.TrimSpace(v.Title + "\n\n" + description)
 			}
 
 			finding := &pb.Finding{
@@ -125,6 +191,28 @@ func (t *TrivyScanner) parseResults(resultsFile string) ([]*pb.Finding, error) {
 				FilePath:    r.Target,
 				CveId:       v.VulnerabilityID,
 			}
+			if len(v.CweIDs) > 0 {
+				finding.CweId = v.CweIDs[0]
+				findingsutil.SetListDetail(finding, findingsutil.DetailCWE, v.CweIDs[1:])
+			}
+			findingsutil.AddReferences(finding, v.PrimaryURL)
+			findingsutil.AddReferences(finding, v.References...)
+
+			setCVSSDetails(finding, v.CVSS, v.SeveritySource)
+			setTrivyPackageDetails(finding, v.PkgName, v.PkgID, v.PkgPath, v.InstalledVersion, v.FixedVersion)
+			for _, detail := range []struct {
+				key   string
+				value string
+			}{
+				{"status", v.Status},
+				{"published", v.PublishedDate},
+				{"last_modified", v.LastModifiedDate},
+				{"data_source", strings.TrimSpace(v.DataSource.Name + " " + v.DataSource.URL)},
+			} {
+				if detail.value != "" {
+					findingsutil.SetDetail(finding, detail.key, detail.value)
+				}
+			}
 
 			findings = append(findings, finding)
 		}
@@ -133,6 +221,79 @@ func (t *TrivyScanner) parseResults(resultsFile string) ([]*pb.Finding, error) {
 	return findings, nil
 }
 
+// setTrivyPackageDetails records the vulnerable package of an SCA finding
+func setTrivyPackageDetails(finding *pb.Finding, name, id, path, installed, fixed string) {
+	pkg := name
+	if id != "" {
+		pkg = id
+	}
+	if path != "" {
+		pkg += " (" + path + ")"
+	}
+	if pkg != "" {
+		findingsutil.SetDetail(finding, findingsutil.DetailPackage, pkg)
+	}
+	if installed != "" {
+		findingsutil.SetDetail(finding, findingsutil.DetailInstalledVersion, installed)
+	}
+	if fixed != "" {
+		findingsutil.SetDetail(finding, findingsutil.DetailFixedVersion, fixed)
+	}
+}
+
+// trivyCVSS is the CVSS data one source reports for a vulnerability
+type trivyCVSS struct {
+	V3Vector  string  `json:"V3Vector"`
+	V3Score   float64 `json:"V3Score"`
+	V40Vector string  `json:"V40Vector"`
+	V40Score  float64 `json:"V40Score"`
+}
+
+// setCVSSDetails records the CVSS v3 and v4 scores and vectors of the most
+// authoritative source: the one Trivy took the severity from, then NVD, then
+// GHSA, then any other
+func setCVSSDetails(finding *pb.Finding, cvss map[string]trivyCVSS, severitySource string) {
+	sources := make([]string, 0, len(cvss))
+	for source := range cvss {
+		sources = append(sources, source)
+	}
+	rank := func(source string) int {
+		switch source {
+		case severitySource:
+			return 0
+		case "nvd":
+			return 1
+		case "ghsa":
+			return 2
+		default:
+			return 3
+		}
+	}
+	sort.Slice(sources, func(i, j int) bool {
+		if rank(sources[i]) != rank(sources[j]) {
+			return rank(sources[i]) < rank(sources[j])
+		}
+		return sources[i] < sources[j]
+	})
+
+	var v3, v4 string
+	for _, source := range sources {
+		c := cvss[source]
+		if v3 == "" && c.V3Score > 0 {
+			v3 = strings.TrimSpace(fmt.Sprintf("%.1f %s", c.V3Score, c.V3Vector))
+		}
+		if v4 == "" && c.V40Score > 0 {
+			v4 = strings.TrimSpace(fmt.Sprintf("%.1f %s", c.V40Score, c.V40Vector))
+		}
+	}
+	if v3 != "" {
+		findingsutil.SetDetail(finding, findingsutil.DetailCVSSv3, v3)
+	}
+	if v4 != "" {
+		findingsutil.SetDetail(finding, findingsutil.DetailCVSSv4, v4)
+	}
+}
+
 // mapSeverity maps Trivy severity to proto severity
 func (t *TrivyScanner) mapSeverity(severity string) pb.Severity {
 	switch severity {
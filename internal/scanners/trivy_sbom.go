@@ -0,0 +1,196 @@
+package scanners
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+
+	pb "github.com/cloud-scan/cloudscan-orchestrator/generated/proto"
+	findingsutil "github.com/cloud-scan/cloudscan-runner/internal/findings"
+	log "github.com/sirupsen/logrus"
+)
+
+func init() {
+	mustRegister(Registration{
+		Name:       "trivy-sbom",
+		ScanTypes:  []pb.ScanType{pb.ScanType_SCA},
+		Priority:   100,
+		Source:     SourceSBOM,
+		MinVersion: "0.37.0",
+		New:        func() Scanner { return NewTrivySBOMScanner(pb.ScanType_SCA) },
+	})
+	mustRegister(Registration{
+		Name:       "trivy-sbom-license",
+		ScanTypes:  []pb.ScanType{pb.ScanType_LICENSE},
+		Priority:   100,
+		Source:     SourceSBOM,
+		MinVersion: "0.37.0",
+		New:        func() Scanner { return NewTrivySBOMScanner(pb.ScanType_LICENSE) },
+	})
+}
+
+// TrivySBOMScanner implements vulnerability (SCA) and license scanning of
+// the components listed in a CycloneDX or SPDX document using Trivy
+type TrivySBOMScanner struct {
+	scanType pb.ScanType
+	trivy    *TrivyScanner
+	logger   *log.Entry
+}
+
+// NewTrivySBOMScanner creates a Trivy SBOM scanner for SCA or LICENSE scans
+func NewTrivySBOMScanner(scanType pb.ScanType) *TrivySBOMScanner {
+	s := &TrivySBOMScanner{
+		scanType: scanType,
+		trivy:    NewTrivyScanner(),
+	}
+	s.logger = log.WithField("scanner", s.Name())
+	return s
+}
+
+// Name returns the scanner name
+func (s *TrivySBOMScanner) Name() string {
+	if s.scanType == pb.ScanType_LICENSE {
+		return "trivy-sbom-license"
+	}
+	return "trivy-sbom"
+}
+
+// ScanType returns the scan type
+func (s *TrivySBOMScanner) ScanType() pb.ScanType {
+	return s.scanType
+}
+
+// IsAvailable checks if trivy is installed
+func (s *TrivySBOMScanner) IsAvailable() bool {
+	return s.trivy.IsAvailable()
+}
+
+// Version returns the installed trivy version
+func (s *TrivySBOMScanner) Version(ctx context.Context) (string, error) {
+	return s.trivy.Version(ctx)
+}
+
+// CheckData checks for the vulnerability database; license scans need none
+func (s *TrivySBOMScanner) CheckData(ctx context.Context) (string, error) {
+	if s.scanType == pb.ScanType_LICENSE {
+		return "", nil
+	}
+	return s.trivy.CheckData(ctx)
+}
+
+// Scan executes a Trivy scan of the SBOM document at sbomPath
+func (s *TrivySBOMScanner) Scan(ctx context.Context, sbomPath string) ([]*pb.Finding, error) {
+	s.logger.WithField("sbom_path", sbomPath).Info("Starting Trivy SBOM scan")
+
+	if !s.IsAvailable() {
+		return nil, fmt.Errorf("trivy is not installed")
+	}
+
+	f, err := os.CreateTemp("", "trivy-sbom-*.json")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create results file: %w", err)
+	}
+	resultsFile := f.Name()
+	f.Close()
+	defer os.Remove(resultsFile)
+
+	trivyScanner := "vuln"
+	if s.scanType == pb.ScanType_LICENSE {
+		trivyScanner = "license"
+	}
+
+	cmd := exec.CommandContext(ctx, "trivy",
+		"sbom",
+		"--format=json",
+		"--output="+resultsFile,
+		"--scanners="+trivyScanner,
+		sbomPath,
+	)
+
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		s.logger.WithError(err).WithField("output", string(output)).Warn("Trivy exited with error (may have findings)")
+	}
+
+	var findings []*pb.Finding
+	if s.scanType == pb.ScanType_LICENSE {
+		findings, err = parseTrivyLicenses(resultsFile)
+	} else {
+		findings, err = s.trivy.parseResults(resultsFile)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse trivy results: %w", err)
+	}
+
+	s.logger.WithField("findings", len(findings)).Info("Trivy SBOM scan complete")
+	return findings, nil
+}
+
+// parseTrivyLicenses parses the Licenses lists of Trivy JSON output
+func parseTrivyLicenses(resultsFile string) ([]*pb.Finding, error) {
+	data, err := os.ReadFile(resultsFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read results: %w", err)
+	}
+
+	var result struct {
+		Results []struct {
+			Target   string `json:"Target"`
+			Licenses []struct {
+				Severity   string  `json:"Severity"`
+				Category   string  `json:"Category"`
+				PkgName    string  `json:"PkgName"`
+				FilePath   string  `json:"FilePath"`
+				Name       string  `json:"Name"`
+				Confidence float64 `json:"Confidence"`
+				Link       string  `json:"Link"`
+			} `json:"Licenses"`
+		} `json:"Results"`
+	}
+
+	if err := json.Unmarshal(data, &result); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal JSON: %w", err)
+	}
+
+	trivy := NewTrivyScanner()
+	findings := make([]*pb.Finding, 0)
+	for _, r := range result.Results {
+		for _, l := range r.Licenses {
+			title := fmt.Sprintf("License: %s", l.Name)
+			description := fmt.Sprintf("License %s", l.Name)
+			if l.PkgName != "" {
+				description += fmt.Sprintf(" declared by package %s", l.PkgName)
+			}
+
+			filePath := l.FilePath
+			if filePath == "" {
+				filePath = r.Target
+			}
+
+			finding := &pb.Finding{
+				ScanType:    pb.ScanType_LICENSE,
+				Severity:    trivy.mapSeverity(l.Severity),
+				Title:       title,
+				Description: description,
+				FilePath:    filePath,
+			}
+			findingsutil.AddReferences(finding, l.Link)
+			if l.Category != "" {
+				findingsutil.SetDetail(finding, findingsutil.DetailLicenseCategory, l.Category)
+			}
+			if l.PkgName != "" {
+				findingsutil.SetDetail(finding, findingsutil.DetailPackage, l.PkgName)
+			}
+			if l.Confidence > 0 {
+				findingsutil.SetDetail(finding, findingsutil.DetailConfidence, strconv.FormatFloat(l.Confidence, 'f', -1, 64))
+			}
+
+			findings = append(findings, finding)
+		}
+	}
+
+	return findings, nil
+}
@@ -2,17 +2,33 @@ package scanners
 
 import (
 	"bufio"
+	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
 
 	pb "github.com/cloud-scan/cloudscan-orchestrator/generated/proto"
+	findingsutil "github.com/cloud-scan/cloudscan-runner/internal/findings"
 	log "github.com/sirupsen/logrus"
 )
 
+func init() {
+	mustRegister(Registration{
+		Name:       "trufflehog",
+		ScanTypes:  []pb.ScanType{pb.ScanType_SECRETS},
+		Priority:   100,
+		MinVersion: "3.0.0",
+		New:        func() Scanner { return NewTruffleHogScanner() },
+	})
+}
+
 // TruffleHogScanner implements secrets detection using TruffleHog
 type TruffleHogScanner struct {
 	logger *log.Entry
@@ -41,10 +57,28 @@ func (t *TruffleHogScanner) IsAvailable() bool {
 	return err == nil
 }
 
+// Version returns the installed trufflehog version
+func (t *TruffleHogScanner) Version(ctx context.Context) (string, error) {
+	return toolVersion(ctx, "trufflehog", "--version")
+}
+
 // Scan executes TruffleHog scan
 func (t *TruffleHogScanner) Scan(ctx context.Context, sourceDir string) ([]*pb.Finding, error) {
 	t.logger.WithField("source_dir", sourceDir).Info("Starting TruffleHog scan")
+	return t.run(ctx, []string{sourceDir})
+}
+
+// ScanFiles executes a TruffleHog scan limited to files relative to sourceDir
+func (t *TruffleHogScanner) ScanFiles(ctx context.Context, sourceDir string, files []string) ([]*pb.Finding, error) {
+	t.logger.WithFields(log.Fields{
+		"source_dir": sourceDir,
+		"files":      len(files),
+	}).Info("Starting targeted TruffleHog scan")
+	return t.run(ctx, targetPaths(sourceDir, files))
+}
 
+// run executes TruffleHog against the given target paths
+func (t *TruffleHogScanner) run(ctx context.Context, targets []string) ([]*pb.Finding, error) {
 	if !t.IsAvailable() {
 		return nil, fmt.Errorf("trufflehog is not installed")
 	}
@@ -54,13 +88,93 @@ func (t *TruffleHogScanner) Scan(ctx context.Context, sourceDir string) ([]*pb.F
 	defer os.Remove(resultsFile)
 
 	// Run trufflehog
-	cmd := exec.CommandContext(ctx, "trufflehog",
+	args := []string{
 		"filesystem",                  // Filesystem scan
 		"--json",                      // JSON output
 		"--no-verification",           // Don't verify secrets (faster)
 		"--no-update",                 // Disable auto-update (prevents exit code 1 in containers)
-		sourceDir,                     // Source directory
-	)
+	}
+	args = append(args, targets...) // Source directory or files
+
+	findings, err := t.stream(ctx, args)
+	if err != nil {
+		return nil, err
+	}
+
+	t.logger.WithField("findings", len(findings)).Info("TruffleHog scan complete")
+	return findings, nil
+}
+
+// ScanHistory executes a TruffleHog scan of every commit in the git
+// repository at repoDir, so secrets that were committed and later deleted
+// are reported too. Findings point at the historical path and carry the
+// commit, author and timestamp. When ctx expires the findings reported so
+// far are returned along with the context error.
+func (t *TruffleHogScanner) ScanHistory(ctx context.Context, repoDir string, opts HistoryOptions) ([]*pb.Finding, error) {
+	t.logger.WithFields(log.Fields{
+		"repo_dir":     repoDir,
+		"since_commit": opts.SinceCommit,
+	}).Info("Starting TruffleHog git history scan")
+
+	if !t.IsAvailable() {
+		return nil, fmt.Errorf("trufflehog is not installed")
+	}
+
+	args := []string{
+		"git",
+		"--json",
+		"--no-verification",
+		"--no-update",
+	}
+	if opts.SinceCommit != "" {
+		args = append(args, "--since-commit="+opts.SinceCommit)
+	}
+	args = append(args, "file://"+repoDir)
+
+	findings, err := t.stream(ctx, args)
+	if err != nil {
+		return nil, err
+	}
+
+	t.logger.WithField("findings", len(findings)).Info("TruffleHog git history scan complete")
+	return findings, ctx.Err()
+}
+
+// truffleHogResult is one line of TruffleHog JSON output
+type truffleHogResult struct {
+	SourceMetadata struct {
+		Data struct {
+			Filesystem struct {
+				File string `json:"file"`
+				Line int    `json:"line"`
+			} `json:"Filesystem"`
+			Git struct {
+				Commit    string `json:"commit"`
+				File      string `json:"file"`
+				Email     string `json:"email"`
+				Timestamp string `json:"timestamp"`
+				Line      int    `json:"line"`
+			} `json:"Git"`
+		} `json:"Data"`
+	} `json:"SourceMetadata"`
+	SourceName   string            `json:"SourceName"`
+	DetectorType int               `json:"DetectorType"`
+	DetectorName string            `json:"DetectorName"`
+	DetectorDesc string            `json:"DetectorDescription"`
+	DecoderName  string            `json:"DecoderName"`
+	Raw          string            `json:"Raw"`
+	Redacted     string            `json:"Redacted"`
+	Verified     bool              `json:"Verified"`
+	ExtraData    map[string]string `json:"ExtraData"`
+}
+
+// stream runs TruffleHog and maps its streaming JSON output onto findings.
+// TruffleHog exits 0 when it reports findings, so any other exit means the
+// scan failed, unless ctx ended it early.
+func (t *TruffleHogScanner) stream(ctx context.Context, args []string) ([]*pb.Finding, error) {
+	cmd := exec.CommandContext(ctx, "trufflehog", args...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
 
 	// Capture output
 	stdout, err := cmd.StdoutPipe()
@@ -81,52 +195,109 @@ func (t *TruffleHogScanner) Scan(ctx context.Context, sourceDir string) ([]*pb.F
 			continue
 		}
 
-		var result struct {
-			SourceMetadata struct {
-				Data struct {
-					Filesystem struct {
-						File string `json:"file"`
-						Line int    `json:"line"`
-					} `json:"Filesystem"`
-				} `json:"Data"`
-			} `json:"SourceMetadata"`
-			SourceName  string `json:"SourceName"`
-			DetectorName string `json:"DetectorName"`
-			Raw         string `json:"Raw"`
-			Verified    bool   `json:"Verified"`
-		}
-
+		var result truffleHogResult
 		if err := json.Unmarshal([]byte(line), &result); err != nil {
 			t.logger.WithError(err).Warn("Failed to parse trufflehog output line")
 			continue
 		}
 
-		title := fmt.Sprintf("Secret detected: %s", result.DetectorName)
-		description := fmt.Sprintf("Potential secret found in source code")
-		if result.Verified {
-			description += " (VERIFIED - this secret is active!)"
+		findings = append(findings, mapTruffleHogResult(&result))
+	}
+
+	if err := scanner.Err(); err != nil {
+		t.logger.WithError(err).Warn("Error reading trufflehog output")
+	}
+
+	if err := cmd.Wait(); err != nil && ctx.Err() == nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && exitErr.ExitCode() > 0 {
+			return nil, exitCodeError("trufflehog", exitErr.ExitCode(), "", stderr.String())
 		}
+		return nil, fmt.Errorf("failed to run trufflehog: %w", err)
+	}
 
-		finding := &pb.Finding{
-			ScanType:    pb.ScanType_SECRETS,
-			Severity:    pb.Severity_HIGH, // Secrets are always high severity
-			Title:       title,
-			Description: description,
-			FilePath:    result.SourceMetadata.Data.Filesystem.File,
-			LineNumber:  int32(result.SourceMetadata.Data.Filesystem.Line),
+	return findings, nil
+}
+
+// mapTruffleHogResult converts one TruffleHog result into a finding
+func mapTruffleHogResult(result *truffleHogResult) *pb.Finding {
+	title := fmt.Sprintf("Secret detected: %s", result.DetectorName)
+	description := fmt.Sprintf("Potential secret found in source code")
+	filePath := result.SourceMetadata.Data.Filesystem.File
+	line := result.SourceMetadata.Data.Filesystem.Line
+
+	history := result.SourceMetadata.Data.Git
+	if history.Commit != "" {
+		description = fmt.Sprintf("Potential secret found in git history (commit %s)", shortSHA(history.Commit))
+		filePath = history.File
+		line = history.Line
+	}
+	if result.Verified {
+		description += " (VERIFIED - this secret is active!)"
+	}
+
+	finding := &pb.Finding{
+		ScanType:    pb.ScanType_SECRETS,
+		Severity:    pb.Severity_HIGH, // Secrets are always high severity
+		Title:       title,
+		Description: description,
+		FilePath:    filePath,
+		LineNumber:  int32(line),
+		CodeSnippet: result.Redacted, // Never the raw secret
+		CweId:       "CWE-798", // Use of hard-coded credentials
+	}
+	if history.Commit != "" {
+		findingsutil.SetDetail(finding, findingsutil.DetailCommit, history.Commit)
+		if history.Email != "" {
+			findingsutil.SetDetail(finding, findingsutil.DetailCommitAuthor, history.Email)
 		}
+		if history.Timestamp != "" {
+			findingsutil.SetDetail(finding, findingsutil.DetailCommitDate, history.Timestamp)
+		}
+	}
+	setTruffleHogDetails(finding, result.DetectorDesc, result.DecoderName, result.Verified, result.ExtraData)
+
+	return finding
+}
 
-		findings = append(findings, finding)
+// shortSHA abbreviates a commit SHA for display
+func shortSHA(sha string) string {
+	if len(sha) > 12 {
+		return sha[:12]
 	}
+	return sha
+}
 
-	if err := scanner.Err(); err != nil {
-		t.logger.WithError(err).Warn("Error reading trufflehog output")
+// setTruffleHogDetails records the detector description and the metadata
+// TruffleHog extracted about the secret. URLs in the extra data (such as
+// detector docs and rotation guides) become references; everything else
+// becomes a detail.
+func setTruffleHogDetails(finding *pb.Finding, description, decoder string, verified bool, extra map[string]string) {
+	keys := make([]string, 0, len(extra))
+	for key := range extra {
+		keys = append(keys, key)
 	}
+	sort.Strings(keys)
 
-	if err := cmd.Wait(); err != nil {
-		t.logger.WithError(err).Warn("TruffleHog exited with error (may have findings)")
+	for _, key := range keys {
+		value := strings.TrimSpace(extra[key])
+		if strings.HasPrefix(value, "https://") || strings.HasPrefix(value, "http://") {
+			findingsutil.AddReferences(finding, value)
+		}
 	}
 
-	t.logger.WithField("findings", len(findings)).Info("TruffleHog scan complete")
-	return findings, nil
+	if description != "" {
+		findingsutil.SetDetail(finding, "detector_description", description)
+	}
+	findingsutil.SetDetail(finding, findingsutil.DetailVerified, strconv.FormatBool(verified))
+	if decoder != "" && decoder != "PLAIN" {
+		findingsutil.SetDetail(finding, "decoder", decoder)
+	}
+	for _, key := range keys {
+		value := strings.TrimSpace(extra[key])
+		if value == "" || strings.HasPrefix(value, "https://") || strings.HasPrefix(value, "http://") {
+			continue
+		}
+		findingsutil.SetDetail(finding, strings.ToLower(strings.ReplaceAll(key, " ", "_")), value)
+	}
 }
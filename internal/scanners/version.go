@@ -0,0 +1,78 @@
+package scanners
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var versionPattern = regexp.MustCompile(`\d+\.\d+(?:\.\d+)?(?:[-+][0-9A-Za-z.-]+)?`)
+
+// toolVersion runs a tool's version command and extracts the first version number
+func toolVersion(ctx context.Context, binary string, args ...string) (string, error) {
+	output, err := exec.CommandContext(ctx, binary, args...).CombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("failed to run %s %s: %w", binary, strings.Join(args, " "), err)
+	}
+
+	version := ExtractVersion(string(output))
+	if version == "" {
+		return "", fmt.Errorf("no version found in %s output", binary)
+	}
+	return version, nil
+}
+
+// ExtractVersion returns the first version number in a tool's output, or
+// an empty string if there is none
+func ExtractVersion(output string) string {
+	return versionPattern.FindString(output)
+}
+
+// CompareVersions compares two version numbers such as "1.2.3" or
+// "v0.58.1-rc1" and returns -1, 0 or +1. Missing components count as zero,
+// a pre-release sorts before its release and build metadata is ignored.
+func CompareVersions(a, b string) int {
+	coreA, preA := splitVersion(a)
+	coreB, preB := splitVersion(b)
+	for i := 0; i < max(len(coreA), len(coreB)); i++ {
+		var x, y int
+		if i < len(coreA) {
+			x = coreA[i]
+		}
+		if i < len(coreB) {
+			y = coreB[i]
+		}
+		if x != y {
+			return cmp.Compare(x, y)
+		}
+	}
+
+	switch {
+	case preA == preB:
+		return 0
+	case preA == "":
+		return 1
+	case preB == "":
+		return -1
+	default:
+		return strings.Compare(preA, preB)
+	}
+}
+
+// splitVersion splits a version into its numeric components and pre-release
+func splitVersion(version string) ([]int, string) {
+	version = strings.TrimPrefix(strings.TrimSpace(version), "v")
+	version, _, _ = strings.Cut(version, "+")
+	version, pre, _ := strings.Cut(version, "-")
+
+	var core []int
+	for _, part := range strings.Split(version, ".") {
+		n, _ := strconv.Atoi(part)
+		core = append(core, n)
+	}
+	return core, pre
+}
@@ -0,0 +1,92 @@
+package snippet
+
+import (
+	"strings"
+)
+
+// locatePackage returns the 1-based line declaring a package in a manifest
+// or lockfile, or 0 when it cannot be found. Format-specific patterns are
+// tried first, then any line naming the package and version, then any line
+// naming the package.
+func locatePackage(fileName string, lines []string, name, version string) int {
+	if name == "" {
+		return 0
+	}
+
+	needles := packageNeedles(strings.ToLower(fileName), name, version)
+	if version != "" {
+		needles = append(needles, needle{all: []string{name, version}})
+	}
+	needles = append(needles, needle{all: []string{`"` + name + `"`}}, needle{all: []string{name}})
+
+	for _, n := range needles {
+		for i, line := range lines {
+			if n.matches(line) {
+				return i + 1
+			}
+		}
+	}
+	return 0
+}
+
+// needle matches a line containing all of its strings, or starting with prefix
+type needle struct {
+	prefix     string
+	all        []string
+	foldCase   bool
+	trimIndent bool
+}
+
+// matches reports whether a line matches the needle
+func (n needle) matches(line string) bool {
+	if n.trimIndent {
+		line = strings.TrimLeft(line, " \t")
+	}
+	if n.foldCase {
+		line = strings.ToLower(line)
+	}
+	if n.prefix != "" && !strings.HasPrefix(line, n.prefix) {
+		return false
+	}
+	for _, s := range n.all {
+		if !strings.Contains(line, s) {
+			return false
+		}
+	}
+	return true
+}
+
+// packageNeedles returns the patterns that locate a package entry in the
+// formats Trivy reads
+func packageNeedles(fileName, name, version string) []needle {
+	switch {
+	case fileName == "package-lock.json" || fileName == "npm-shrinkwrap.json":
+		return []needle{
+			{all: []string{`"node_modules/` + name + `":`}},
+			{all: []string{`"` + name + `": {`}},
+		}
+	case fileName == "yarn.lock":
+		return []needle{{prefix: name + "@"}, {prefix: `"` + name + "@"}}
+	case fileName == "pnpm-lock.yaml":
+		return []needle{{all: []string{name + "@" + version}, trimIndent: true}, {prefix: "/" + name + "/" + version, trimIndent: true}}
+	case fileName == "go.mod" || fileName == "go.sum":
+		return []needle{{all: []string{name + " " + version}, trimIndent: true}}
+	case fileName == "cargo.lock" || fileName == "poetry.lock" || fileName == "uv.lock":
+		return []needle{{all: []string{`name = "` + name + `"`}}}
+	case fileName == "gemfile.lock":
+		return []needle{{prefix: name + " (" + version, trimIndent: true}}
+	case fileName == "composer.lock":
+		return []needle{{all: []string{`"name": "` + name + `"`}}}
+	case fileName == "pipfile.lock":
+		return []needle{{all: []string{`"` + strings.ToLower(name) + `": {`}, foldCase: true}}
+	case strings.HasPrefix(fileName, "requirements") && strings.HasSuffix(fileName, ".txt"):
+		lower := strings.ToLower(name)
+		return []needle{{prefix: lower + "==", foldCase: true}, {prefix: lower, foldCase: true}}
+	case fileName == "pom.xml" || strings.HasSuffix(fileName, ".gradle") || strings.HasSuffix(fileName, ".gradle.kts"):
+		// Maven packages are named "<group>:<artifact>"
+		artifact := name[strings.LastIndex(name, ":")+1:]
+		return []needle{{all: []string{"<artifactId>" + artifact + "</artifactId>"}}, {all: []string{":" + artifact + ":"}}}
+	default:
+		return nil
+	}
+}
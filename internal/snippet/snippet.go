@@ -0,0 +1,253 @@
+// Package snippet fills finding code snippets from the scanned workspace,
+// adding context lines around the reported location.
+package snippet
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	pb "github.com/cloud-scan/cloudscan-orchestrator/generated/proto"
+	"github.com/cloud-scan/cloudscan-runner/internal/findings"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// DefaultContextLines is the number of lines shown before and after a match
+	DefaultContextLines = 3
+	// DefaultMaxBytes caps the size of a snippet
+	DefaultMaxBytes = 4096
+
+	// maxMatchLines caps the matched lines kept from a multi-line finding
+	maxMatchLines = 50
+	// maxLineLength truncates long (e.g. minified) lines
+	maxLineLength = 400
+	// binarySniffSize is how much of a file is checked for NUL bytes
+	binarySniffSize = 8000
+	// maxFileSize skips files too large to be worth reading
+	maxFileSize = 16 << 20
+)
+
+// errBinary reports a file that is not UTF-8 text
+var errBinary = errors.New("binary file")
+
+// Extractor reads snippets from files under a source directory
+type Extractor struct {
+	sourceDir    string
+	contextLines int
+	maxBytes     int
+	logger       *log.Entry
+}
+
+// Stats summarizes an extraction pass
+type Stats struct {
+	Filled  int // Findings given a snippet
+	Located int // SCA findings located in their lockfile
+	Skipped int // Findings whose file could not be read or is binary
+}
+
+// New creates an extractor for files under sourceDir showing contextLines
+// lines around each match; a non-positive maxBytes selects DefaultMaxBytes
+func New(sourceDir string, contextLines, maxBytes int) *Extractor {
+	if maxBytes <= 0 {
+		maxBytes = DefaultMaxBytes
+	}
+	if contextLines < 0 {
+		contextLines = 0
+	}
+	return &Extractor{
+		sourceDir:    sourceDir,
+		contextLines: contextLines,
+		maxBytes:     maxBytes,
+		logger:       log.WithField("component", "snippet"),
+	}
+}
+
+// Fill replaces the snippet of every finding that names a file in the
+// workspace with the matched lines and their context. SCA findings without
+// a line number are first located in their manifest or lockfile. SECRETS
+// findings keep the scanner's redacted snippet, since context lines would
+// carry the secret itself.
+func (e *Extractor) Fill(results []*pb.Finding) Stats {
+	var stats Stats
+	for _, f := range results {
+		// Findings from past commits do not match the checked-out files
+		if f.FilePath == "" || f.ScanType == pb.ScanType_SECRETS || findings.FromHistory(f) {
+			continue
+		}
+		path, ok := e.resolve(f.FilePath)
+		if !ok {
+			continue
+		}
+
+		lines, err := readLines(path)
+		if err != nil {
+			if !errors.Is(err, os.ErrNotExist) {
+				e.logger.WithError(err).WithField("file", f.FilePath).Debug("Skipping snippet")
+			}
+			stats.Skipped++
+			continue
+		}
+
+		if f.LineNumber <= 0 && f.ScanType == pb.ScanType_SCA {
+			name, version := findings.Package(f)
+			if line := locatePackage(filepath.Base(path), lines, name, version); line > 0 {
+				f.LineNumber = int32(line)
+				stats.Located++
+			}
+		}
+		if f.LineNumber <= 0 || int(f.LineNumber) > len(lines) {
+			continue
+		}
+
+		first, last, snippet := e.extract(f, lines)
+		f.CodeSnippet = snippet
+		findings.SetDetail(f, findings.DetailSnippetLines, fmt.Sprintf("%d-%d", first, last))
+		stats.Filled++
+	}
+	return stats
+}
+
+// resolve maps a finding path onto a file inside the source directory.
+// Symlinks are followed only while they stay inside it, so a malicious
+// repository cannot pull other files of the runner into a snippet.
+func (e *Extractor) resolve(path string) (string, bool) {
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(e.sourceDir, filepath.FromSlash(path))
+	}
+	root, err := filepath.EvalSymlinks(e.sourceDir)
+	if err != nil {
+		return "", false
+	}
+	resolved, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		return "", false
+	}
+	rel, err := filepath.Rel(root, resolved)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", false
+	}
+	return resolved, true
+}
+
+// extract returns the line range and text of the matched lines plus as many
+// context lines as fit within the size cap
+func (e *Extractor) extract(f *pb.Finding, lines []string) (int, int, string) {
+	start := int(f.LineNumber)
+	end := start
+	if n, err := strconv.Atoi(findings.Detail(f, findings.DetailEndLine)); err == nil && n > start {
+		end = min(n, len(lines), start+maxMatchLines-1)
+	}
+
+	size := 0
+	for i := start; i <= end; i++ {
+		size += len(clip(lines[i-1])) + 1
+	}
+	// The matched lines always stay; trim them only if they alone exceed the cap
+	for size > e.maxBytes && end > start {
+		size -= len(clip(lines[end-1])) + 1
+		end--
+	}
+
+	first, last := start, end
+	for i := 1; i <= e.contextLines; i++ {
+		grew := false
+		if first > 1 && size+len(clip(lines[first-2]))+1 <= e.maxBytes {
+			first--
+			size += len(clip(lines[first-1])) + 1
+			grew = true
+		}
+		if last < len(lines) && size+len(clip(lines[last]))+1 <= e.maxBytes {
+			last++
+			size += len(clip(lines[last-1])) + 1
+			grew = true
+		}
+		if !grew {
+			break
+		}
+	}
+
+	out := make([]string, 0, last-first+1)
+	for i := first; i <= last; i++ {
+		out = append(out, clip(lines[i-1]))
+	}
+	text := strings.Join(out, "\n")
+	if len(text) > e.maxBytes {
+		text = truncate(text, e.maxBytes) + " …"
+	}
+	// Snippets travel in proto strings, which must be valid UTF-8
+	return first, last, strings.ToValidUTF8(text, "")
+}
+
+// clip truncates a long line
+func clip(line string) string {
+	if len(line) <= maxLineLength {
+		return line
+	}
+	return truncate(line, maxLineLength) + " …"
+}
+
+// truncate cuts s to at most n bytes without splitting a UTF-8 sequence
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
+
+// readLines reads a UTF-8 text file; binary files and files in other
+// encodings yield errBinary
+func readLines(path string) ([]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+	if !info.Mode().IsRegular() {
+		return nil, fmt.Errorf("%s is not a regular file", path)
+	}
+	if info.Size() > maxFileSize {
+		return nil, fmt.Errorf("%s is larger than %d bytes", path, maxFileSize)
+	}
+
+	reader := bufio.NewReaderSize(file, binarySniffSize)
+	head, err := reader.Peek(binarySniffSize)
+	if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, bufio.ErrBufferFull) {
+		return nil, err
+	}
+	if bytes.IndexByte(head, 0) >= 0 {
+		return nil, errBinary
+	}
+
+	var lines []string
+	for {
+		line, err := reader.ReadString('\n')
+		if !utf8.ValidString(line) {
+			return nil, errBinary
+		}
+		if line != "" || err == nil {
+			lines = append(lines, strings.TrimRight(line, "\r\n"))
+		}
+		if errors.Is(err, io.EOF) {
+			return lines, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+}
@@ -0,0 +1,304 @@
+// Package suppress applies the .cloudscanignore suppression file of a
+// repository to scan findings.
+package suppress
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	pb "github.com/cloud-scan/cloudscan-orchestrator/generated/proto"
+	"github.com/cloud-scan/cloudscan-runner/internal/findings"
+	"github.com/cloud-scan/cloudscan-runner/internal/glob"
+	"github.com/cloud-scan/cloudscan-runner/internal/scanners"
+	"gopkg.in/yaml.v3"
+)
+
+// FileName is the suppression file read from the repository root
+const FileName = ".cloudscanignore"
+
+// dateLayout is the format of expiry dates
+const dateLayout = "2006-01-02"
+
+// notePrefix starts the description of findings kept in ModeInfo
+const notePrefix = "[suppressed by "
+
+// Mode controls what happens to suppressed findings
+type Mode string
+
+const (
+	// ModeDrop removes suppressed findings
+	ModeDrop Mode = "drop"
+	// ModeInfo keeps suppressed findings at INFO severity with a note
+	ModeInfo Mode = "info"
+)
+
+// ParseMode parses a suppression mode name
+func ParseMode(mode string) (Mode, error) {
+	switch Mode(strings.ToLower(strings.TrimSpace(mode))) {
+	case ModeDrop, "":
+		return ModeDrop, nil
+	case ModeInfo:
+		return ModeInfo, nil
+	default:
+		return "", fmt.Errorf("unknown suppression mode %q (expected %q or %q)", mode, ModeDrop, ModeInfo)
+	}
+}
+
+// File is a parsed suppression file
+type File struct {
+	Suppressions []*Entry `yaml:"suppressions"`
+}
+
+// Entry suppresses the findings matching all of its criteria
+type Entry struct {
+	RuleID      string `yaml:"rule_id"`
+	CVEID       string `yaml:"cve_id"`
+	Path        string `yaml:"path"` // Glob relative to the repository root
+	Detector    string `yaml:"detector"`
+	Fingerprint string `yaml:"fingerprint"`
+	ScanType    string `yaml:"scan_type"`
+
+	// Reason justifies the suppression (required)
+	Reason string `yaml:"reason"`
+
+	// Expires is the last day (YYYY-MM-DD, UTC) the suppression applies
+	Expires string `yaml:"expires"`
+
+	line     int
+	path     *glob.Pattern
+	scanType pb.ScanType
+	expires  time.Time
+}
+
+// Load reads a suppression file; a missing file yields nil and no error
+func Load(path string) (*File, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read suppression file: %w", err)
+	}
+	return Parse(data)
+}
+
+// Parse parses and validates a suppression file
+func Parse(data []byte) (*File, error) {
+	f := &File{}
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(f); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("failed to parse suppression file: %w", err)
+	}
+
+	// Decode again as a node tree to learn the line of each entry
+	var root yaml.Node
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		return nil, fmt.Errorf("failed to parse suppression file: %w", err)
+	}
+	lines := entryLines(&root)
+
+	var errs []error
+	for i, e := range f.Suppressions {
+		if e == nil {
+			errs = append(errs, fmt.Errorf("suppression %d is empty", i+1))
+			continue
+		}
+		if i < len(lines) {
+			e.line = lines[i]
+		}
+		if err := e.validate(); err != nil {
+			errs = append(errs, fmt.Errorf("line %d: %w", e.line, err))
+		}
+	}
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+
+	return f, nil
+}
+
+// entryLines returns the line of every item of the suppressions sequence
+func entryLines(root *yaml.Node) []int {
+	if root.Kind != yaml.DocumentNode || len(root.Content) == 0 {
+		return nil
+	}
+	mapping := root.Content[0]
+	if mapping.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value != "suppressions" {
+			continue
+		}
+		var lines []int
+		for _, item := range mapping.Content[i+1].Content {
+			lines = append(lines, item.Line)
+		}
+		return lines
+	}
+	return nil
+}
+
+// validate checks required fields and compiles the criteria
+func (e *Entry) validate() error {
+	if strings.TrimSpace(e.Reason) == "" {
+		return fmt.Errorf("suppression is missing reason")
+	}
+	if e.RuleID == "" && e.CVEID == "" && e.Path == "" && e.Detector == "" && e.Fingerprint == "" {
+		return fmt.Errorf("suppression needs at least one of rule_id, cve_id, path, detector or fingerprint")
+	}
+
+	if e.Path != "" {
+		p, err := glob.Compile(e.Path)
+		if err != nil {
+			return err
+		}
+		e.path = p
+	}
+
+	if e.ScanType != "" {
+		scanType, ok := scanners.ParseScanType(e.ScanType)
+		if !ok {
+			return fmt.Errorf("unknown scan_type %q", e.ScanType)
+		}
+		e.scanType = scanType
+	}
+
+	if e.Expires != "" {
+		expires, err := time.Parse(dateLayout, e.Expires)
+		if err != nil {
+			return fmt.Errorf("invalid expires %q (expected YYYY-MM-DD)", e.Expires)
+		}
+		e.expires = expires
+	}
+
+	return nil
+}
+
+// Expired reports whether the suppression no longer applies at now
+func (e *Entry) Expired(now time.Time) bool {
+	return !e.expires.IsZero() && !now.UTC().Before(e.expires.AddDate(0, 0, 1))
+}
+
+// Matches reports whether the entry's criteria all match a finding
+func (e *Entry) Matches(f *pb.Finding, sourceDir string) bool {
+	if e.scanType != pb.ScanType_SCAN_TYPE_UNSPECIFIED && f.ScanType != e.scanType {
+		return false
+	}
+	if e.RuleID != "" && findings.RuleID(f) != e.RuleID {
+		return false
+	}
+	if e.CVEID != "" && !strings.EqualFold(f.CveId, e.CVEID) {
+		return false
+	}
+	if e.Detector != "" && !strings.EqualFold(findings.Detector(f), e.Detector) {
+		return false
+	}
+	if e.path != nil && !e.path.Match(findings.NormalizePath(f.FilePath, sourceDir)) {
+		return false
+	}
+	if e.Fingerprint != "" && findings.Fingerprint(f, sourceDir) != e.Fingerprint {
+		return false
+	}
+	return true
+}
+
+// Apply suppresses matching findings using the entries still active at now.
+// In ModeDrop suppressed findings are removed; in ModeInfo they are kept at
+// INFO severity with the reason noted in the description. It returns the
+// remaining findings and the number suppressed.
+func (f *File) Apply(results []*pb.Finding, sourceDir string, mode Mode, now time.Time) ([]*pb.Finding, int) {
+	kept := make([]*pb.Finding, 0, len(results))
+	suppressed := 0
+
+	for _, finding := range results {
+		entry := f.match(finding, sourceDir, now)
+		if entry == nil {
+			kept = append(kept, finding)
+			continue
+		}
+
+		suppressed++
+		if mode == ModeInfo {
+			finding.Severity = pb.Severity_INFO
+			finding.Description = fmt.Sprintf("%s%s:%d] %s\n\n%s", notePrefix, FileName, entry.line, entry.Reason, finding.Description)
+			kept = append(kept, finding)
+		}
+	}
+
+	return kept, suppressed
+}
+
+// IsSuppressed reports whether a finding was kept in ModeInfo by a suppression
+func IsSuppressed(f *pb.Finding) bool {
+	return strings.HasPrefix(f.Description, notePrefix)
+}
+
+// match returns the first active entry matching a finding
+func (f *File) match(finding *pb.Finding, sourceDir string, now time.Time) *Entry {
+	for _, e := range f.Suppressions {
+		if !e.Expired(now) && e.Matches(finding, sourceDir) {
+			return e
+		}
+	}
+	return nil
+}
+
+// ExpiredFindings reports every expired suppression as a finding of its own
+// so it is reviewed rather than silently ignored
+func (f *File) ExpiredFindings(now time.Time) []*pb.Finding {
+	var expired []*pb.Finding
+	for _, e := range f.Suppressions {
+		if !e.Expired(now) {
+			continue
+		}
+		expired = append(expired, &pb.Finding{
+			ScanType: e.expiredScanType(),
+			Severity: pb.Severity_LOW,
+			Title:    "Expired suppression",
+			Description: fmt.Sprintf("Suppression %s expired on %s and no longer applies. Fix the matching findings, "+
+				"or renew the suppression with a new expiry date.\n\nReason given: %s", e.criteria(), e.Expires, e.Reason),
+			FilePath:    FileName,
+			LineNumber:  int32(e.line),
+			CodeSnippet: e.criteria(),
+		})
+	}
+	return expired
+}
+
+// expiredScanType picks the scan type an expired suppression is reported under
+func (e *Entry) expiredScanType() pb.ScanType {
+	switch {
+	case e.scanType != pb.ScanType_SCAN_TYPE_UNSPECIFIED:
+		return e.scanType
+	case e.CVEID != "":
+		return pb.ScanType_SCA
+	case e.Detector != "":
+		return pb.ScanType_SECRETS
+	default:
+		return pb.ScanType_SAST
+	}
+}
+
+// criteria describes what an entry matches
+func (e *Entry) criteria() string {
+	var parts []string
+	add := func(name, value string) {
+		if value != "" {
+			parts = append(parts, fmt.Sprintf("%s=%s", name, value))
+		}
+	}
+	add("scan_type", e.ScanType)
+	add("rule_id", e.RuleID)
+	add("cve_id", e.CVEID)
+	add("path", e.Path)
+	add("detector", e.Detector)
+	add("fingerprint", e.Fingerprint)
+	return "(" + strings.Join(parts, ", ") + ")"
+}